@@ -0,0 +1,118 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/zellyn/trifle/internal/apidoc"
+	"github.com/zellyn/trifle/internal/auth"
+	"github.com/zellyn/trifle/internal/buildinfo"
+	"github.com/zellyn/trifle/internal/kv"
+	"github.com/zellyn/trifle/internal/maintenance"
+)
+
+// apiSpec describes the /api/ and /kv endpoints served at /api/openapi.json.
+// TestAPISpec_CoversRoutes fails when main.go registers a route that isn't
+// described here, or this describes one that isn't registered.
+func apiSpec() *apidoc.Spec {
+	kvErrors := []int{http.StatusUnauthorized, http.StatusForbidden, http.StatusServiceUnavailable}
+
+	return &apidoc.Spec{
+		Title:   "Trifle API",
+		Version: buildinfo.Get().Version,
+		Operations: []apidoc.Operation{
+			{
+				Method:   http.MethodGet,
+				Path:     "/api/openapi.json",
+				Summary:  "This OpenAPI document",
+				Response: map[string]any{},
+			},
+			{
+				Method:   http.MethodGet,
+				Path:     "/api/whoami",
+				Summary:  "The logged-in user",
+				Auth:     apidoc.AuthSession,
+				Response: auth.WhoAmIResponse{},
+				Errors:   []int{http.StatusUnauthorized},
+			},
+			{
+				Method:   http.MethodGet,
+				Path:     "/api/status",
+				Summary:  "Public server status, polled by the app",
+				Response: maintenance.Status{},
+			},
+			{
+				Method:   http.MethodGet,
+				Path:     "/api/version",
+				Summary:  "Build information of the running server",
+				Response: buildinfo.Info{},
+			},
+			{
+				Method:   http.MethodGet,
+				Path:     "/kv/{key}",
+				Summary:  "Read a value",
+				Auth:     apidoc.AuthSession,
+				Response: apidoc.Raw{},
+				Errors:   append([]int{http.StatusNotFound}, kvErrors...),
+			},
+			{
+				Method:      http.MethodPut,
+				Path:        "/kv/{key}",
+				Summary:     "Write a value",
+				Description: "Keys under file/ are content-addressed: writing one that exists succeeds without changing it.",
+				Auth:        apidoc.AuthSession,
+				Request:     apidoc.Raw{},
+				Errors:      append([]int{http.StatusBadRequest, http.StatusRequestEntityTooLarge}, kvErrors...),
+			},
+			{
+				Method:  http.MethodDelete,
+				Path:    "/kv/{key}",
+				Summary: "Delete a key, or a prefix and everything under it",
+				Auth:    apidoc.AuthSession,
+				Status:  http.StatusNoContent,
+				Errors:  append([]int{http.StatusNotFound}, kvErrors...),
+			},
+			{
+				Method:  http.MethodHead,
+				Path:    "/kv/{key}",
+				Summary: "Check whether a key exists",
+				Auth:    apidoc.AuthSession,
+				Errors:  append([]int{http.StatusNotFound}, kvErrors...),
+			},
+			{
+				Method:  http.MethodGet,
+				Path:    "/kvlist/{prefix}",
+				Summary: "List keys under a prefix",
+				Auth:    apidoc.AuthSession,
+				Query: []apidoc.Param{
+					{Name: "depth", Type: "integer", Description: "Directory levels to descend (default 1)"},
+					{Name: "recursive", Type: "boolean", Description: "List every key under the prefix"},
+				},
+				Response: []string{},
+				Errors:   append([]int{http.StatusBadRequest}, kvErrors...),
+			},
+			{
+				Method:      http.MethodPost,
+				Path:        "/kvsync/manifest",
+				Summary:     "Compare the client's hashes under a prefix with the server's",
+				Description: "Keys in the request must be under the prefix.",
+				Auth:        apidoc.AuthSession,
+				Query:       []apidoc.Param{{Name: "include_values", Type: "boolean", Description: "Inline values up to 64KB"}},
+				Request:     kv.ManifestRequest{},
+				Response:    kv.ManifestResponse{},
+				Errors:      append([]int{http.StatusBadRequest, http.StatusRequestEntityTooLarge, http.StatusUnsupportedMediaType}, kvErrors...),
+				MessagePack: true,
+			},
+			{
+				Method:      http.MethodPost,
+				Path:        "/kvexists",
+				Summary:     "Check which of a batch of keys exist",
+				Description: "Keys the user may not read are reported as forbidden rather than failing the batch.",
+				Auth:        apidoc.AuthSession,
+				Request:     []string{},
+				Response:    []kv.ExistsResult{},
+				Errors:      append([]int{http.StatusBadRequest, http.StatusRequestEntityTooLarge, http.StatusUnsupportedMediaType}, kvErrors...),
+				MessagePack: true,
+			},
+		},
+	}
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"encoding/json"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// routeSources are the files that register routes on the server's mux
+var routeSources = []string{"main.go", "internal/routes/routes.go"}
+
+// registeredAPIRoutes returns the patterns the server registers under /api/
+// and /kv, read from the source since ServeMux can't list its routes
+func registeredAPIRoutes(t *testing.T) map[string]bool {
+	t.Helper()
+	routes := make(map[string]bool)
+	for _, name := range routeSources {
+		file, err := parser.ParseFile(token.NewFileSet(), name, nil, 0)
+		if err != nil {
+			t.Fatalf("Failed to parse %s: %v", name, err)
+		}
+		collectAPIRoutes(t, file, routes)
+	}
+	return routes
+}
+
+// collectAPIRoutes adds the /api/ and /kv patterns registered on a "mux"
+// variable in file to routes
+func collectAPIRoutes(t *testing.T, file *ast.File, routes map[string]bool) {
+	ast.Inspect(file, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok || len(call.Args) == 0 {
+			return true
+		}
+		sel, ok := call.Fun.(*ast.SelectorExpr)
+		if !ok || (sel.Sel.Name != "Handle" && sel.Sel.Name != "HandleFunc") {
+			return true
+		}
+		if x, ok := sel.X.(*ast.Ident); !ok || x.Name != "mux" {
+			return true
+		}
+		lit, ok := call.Args[0].(*ast.BasicLit)
+		if !ok || lit.Kind != token.STRING {
+			return true
+		}
+		pattern, err := strconv.Unquote(lit.Value)
+		if err != nil {
+			t.Fatalf("Bad route pattern %s: %v", lit.Value, err)
+		}
+		// Drop any method, e.g. "GET /api/x"
+		if _, path, ok := strings.Cut(pattern, " "); ok {
+			pattern = path
+		}
+		if strings.HasPrefix(pattern, "/api/") || strings.HasPrefix(pattern, "/kv") {
+			routes[pattern] = true
+		}
+		return true
+	})
+}
+
+// TestAPISpec_CoversRoutes checks the served OpenAPI document against the
+// running server: every /api/ and /kv route the server registers must be
+// documented with exactly the methods it answers to in its Allow header.
+// HEAD is implied wherever GET is documented.
+func TestAPISpec_CoversRoutes(t *testing.T) {
+	routes := registeredAPIRoutes(t)
+	if len(routes) == 0 {
+		t.Fatal("Expected to find API routes in the server source")
+	}
+
+	base := "http://" + startTestServer(t)
+	client := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+
+	resp, err := client.Get(base + "/api/openapi.json")
+	if err != nil {
+		t.Fatalf("GET /api/openapi.json failed: %v", err)
+	}
+	var doc struct {
+		Paths map[string]map[string]json.RawMessage `json:"paths"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&doc)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatalf("Failed to decode OpenAPI document: %v", err)
+	}
+
+	// "/kv/{key}" is served by the subtree pattern "/kv/"
+	documented := make(map[string][]string)
+	for path, item := range doc.Paths {
+		pattern, _, _ := strings.Cut(path, "{")
+		for method := range item {
+			method = strings.ToUpper(method)
+			if !slices.Contains(httpMethods, method) {
+				continue // e.g. shared "parameters"
+			}
+			documented[pattern] = append(documented[pattern], method)
+			if method == http.MethodGet {
+				documented[pattern] = append(documented[pattern], http.MethodHead)
+			}
+		}
+		if !routes[pattern] {
+			t.Errorf("%s is documented but the server registers no %q route", path, pattern)
+		}
+	}
+
+	for pattern := range routes {
+		url := base + pattern
+		if strings.HasSuffix(pattern, "/") {
+			url += "x"
+		}
+		req, _ := http.NewRequest(http.MethodOptions, url, nil)
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("OPTIONS %s failed: %v", pattern, err)
+		}
+		resp.Body.Close()
+
+		var allowed []string
+		for method := range strings.SplitSeq(resp.Header.Get("Allow"), ",") {
+			if method = strings.TrimSpace(method); method != "" && method != http.MethodOptions {
+				allowed = append(allowed, method)
+			}
+		}
+		want := documented[pattern]
+		for _, method := range allowed {
+			if !slices.Contains(want, method) {
+				t.Errorf("%s %s is allowed by the server but missing from apiSpec", method, pattern)
+			}
+		}
+		for _, method := range want {
+			if !slices.Contains(allowed, method) {
+				t.Errorf("%s %s is documented but the route doesn't allow it", method, pattern)
+			}
+		}
+	}
+}
+
+// httpMethods are the operation keys an OpenAPI path item may have
+var httpMethods = []string{
+	http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut,
+	http.MethodPatch, http.MethodDelete, http.MethodOptions, http.MethodTrace,
+}
+
+func TestAPISpec_Builds(t *testing.T) {
+	if _, err := apiSpec().Document(); err != nil {
+		t.Fatalf("Failed to build OpenAPI document: %v", err)
+	}
+}
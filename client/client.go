@@ -0,0 +1,178 @@
+// Package client is a Go client for a trifle server's HTTP API.
+//
+// It covers the sync endpoints (KV values, content-addressed files,
+// listings, and manifest and existence checks) and the public status
+// endpoints. It depends only on the standard library.
+//
+// KV endpoints need a logged-in session: log in through the browser flow
+// and pass an http.Client whose cookie jar holds the trifle_session cookie.
+// Admin endpoints also accept the server's admin token (Options.Token).
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Errors returned (wrapped in *Error) for the corresponding HTTP statuses;
+// test for them with errors.Is
+var (
+	ErrUnauthorized = errors.New("not authenticated")
+	ErrForbidden    = errors.New("forbidden")
+	ErrNotFound     = errors.New("not found")
+	ErrConflict     = errors.New("conflict")
+	ErrUnavailable  = errors.New("server unavailable")
+)
+
+// Error is a non-success response from the server
+type Error struct {
+	StatusCode int
+	Message    string // from the JSON error envelope or the text body
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("trifle: %d %s: %s", e.StatusCode, http.StatusText(e.StatusCode), e.Message)
+}
+
+// Unwrap maps the status code to one of the Err* sentinels, if any
+func (e *Error) Unwrap() error {
+	switch e.StatusCode {
+	case http.StatusUnauthorized:
+		return ErrUnauthorized
+	case http.StatusForbidden:
+		return ErrForbidden
+	case http.StatusNotFound:
+		return ErrNotFound
+	case http.StatusConflict:
+		return ErrConflict
+	case http.StatusServiceUnavailable:
+		return ErrUnavailable
+	}
+	return nil
+}
+
+// Options configure a Client. The zero value uses http.DefaultClient.
+type Options struct {
+	// HTTPClient sends requests. Give it a cookie jar holding a session
+	// cookie to use the KV endpoints.
+	HTTPClient *http.Client
+
+	// Token, if set, is sent as "Authorization: Bearer <token>". The server
+	// accepts only its admin token, and only on admin endpoints.
+	Token string
+}
+
+// Client talks to one trifle server. It is safe for concurrent use.
+type Client struct {
+	baseURL *url.URL
+	http    *http.Client
+	token   string
+}
+
+// New creates a client for the server at baseURL, e.g. "https://trifle.example.com"
+func New(baseURL string, opts Options) (*Client, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid base URL: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("invalid base URL %q: scheme must be http or https", baseURL)
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/")
+
+	httpClient := opts.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &Client{baseURL: u, http: httpClient, token: opts.Token}, nil
+}
+
+// request describes one API call
+type request struct {
+	method      string
+	path        string // unescaped path, relative to the base URL
+	query       url.Values
+	body        io.Reader
+	contentType string
+}
+
+// do sends a request and returns the response if its status is 2xx. The
+// caller must close the body.
+func (c *Client) do(ctx context.Context, req request) (*http.Response, error) {
+	u := *c.baseURL
+	u.Path += req.path
+	u.RawPath = ""
+	u.RawQuery = req.query.Encode()
+
+	httpReq, err := http.NewRequestWithContext(ctx, req.method, u.String(), req.body)
+	if err != nil {
+		return nil, err
+	}
+	if req.contentType != "" {
+		httpReq.Header.Set("Content-Type", req.contentType)
+	}
+	if c.token != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.http.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		defer resp.Body.Close()
+		return nil, responseError(resp)
+	}
+	return resp, nil
+}
+
+// doJSON sends in (if non-nil) as JSON and decodes the response into out
+// (if non-nil)
+func (c *Client) doJSON(ctx context.Context, method, path string, query url.Values, in, out any) error {
+	req := request{method: method, path: path, query: query}
+	if in != nil {
+		body, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		req.body = bytes.NewReader(body)
+		req.contentType = "application/json"
+	}
+
+	resp, err := c.do(ctx, req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode %s response: %w", path, err)
+	}
+	return nil
+}
+
+// responseError builds an *Error from a failed response, taking the message
+// from a JSON {"error": ...} envelope or else the text body
+func responseError(resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	message := strings.TrimSpace(string(body))
+
+	var envelope struct {
+		Error string `json:"error"`
+	}
+	if strings.HasPrefix(resp.Header.Get("Content-Type"), "application/json") &&
+		json.Unmarshal(body, &envelope) == nil && envelope.Error != "" {
+		message = envelope.Error
+	}
+	return &Error{StatusCode: resp.StatusCode, Message: message}
+}
@@ -0,0 +1,193 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/zellyn/trifle/internal/auth"
+	"github.com/zellyn/trifle/internal/kv"
+	"github.com/zellyn/trifle/internal/maintenance"
+	"github.com/zellyn/trifle/internal/routes"
+)
+
+// newTestServer runs the real API and KV routes, registered by the same
+// code as in main.go. GET /test/login?email=... stands in for the OAuth flow.
+func newTestServer(t *testing.T) (*httptest.Server, *maintenance.Mode) {
+	t.Helper()
+	store, err := kv.NewStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	sessionMgr := auth.NewSessionManager(false, time.Hour)
+	maintenanceMode := maintenance.New(false)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/test/login", func(w http.ResponseWriter, r *http.Request) {
+		session, err := sessionMgr.GetOrCreateSession(r, w)
+		if err != nil {
+			t.Errorf("Failed to create session: %v", err)
+			return
+		}
+		session.Email = r.URL.Query().Get("email")
+		session.Authenticated = true
+		sessionMgr.Save(w, r, session)
+	})
+	routes.API{
+		Sessions:      sessionMgr,
+		Maintenance:   maintenanceMode,
+		KV:            kv.NewHandlers(store),
+		UploadTimeout: time.Minute,
+	}.Register(mux)
+
+	server := httptest.NewServer(maintenanceMode.Middleware("/api/", "/kv/")(mux))
+	t.Cleanup(server.Close)
+	return server, maintenanceMode
+}
+
+// newLoggedInClient returns a client whose cookie jar holds a session for email
+func newLoggedInClient(t *testing.T, server *httptest.Server, email string) *Client {
+	t.Helper()
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		t.Fatalf("Failed to create cookie jar: %v", err)
+	}
+	httpClient := &http.Client{Jar: jar}
+	resp, err := httpClient.Get(server.URL + "/test/login?email=" + email)
+	if err != nil {
+		t.Fatalf("Login failed: %v", err)
+	}
+	resp.Body.Close()
+
+	c, err := New(server.URL, Options{HTTPClient: httpClient})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	return c
+}
+
+func TestClient_KV(t *testing.T) {
+	server, _ := newTestServer(t)
+	c := newLoggedInClient(t, server, "alice@example.com")
+	ctx := context.Background()
+
+	email, err := c.WhoAmI(ctx)
+	if err != nil || email != "alice@example.com" {
+		t.Fatalf("Expected alice@example.com, got %q (%v)", email, err)
+	}
+	prefix, err := UserPrefix(email)
+	if err != nil {
+		t.Fatalf("UserPrefix failed: %v", err)
+	}
+
+	key := prefix + "/profile/name+tag"
+	if err := c.Put(ctx, key, []byte("Alice")); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	if value, err := c.Get(ctx, key); err != nil || string(value) != "Alice" {
+		t.Errorf("Expected Alice, got %q (%v)", value, err)
+	}
+	if ok, err := c.Exists(ctx, key); err != nil || !ok {
+		t.Errorf("Expected key to exist, got %v (%v)", ok, err)
+	}
+
+	keys, err := c.List(ctx, prefix, ListOptions{Recursive: true})
+	if err != nil || !slices.Equal(keys, []string{key}) {
+		t.Errorf("Expected [%s], got %v (%v)", key, keys, err)
+	}
+
+	if err := c.Delete(ctx, key); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if ok, err := c.Exists(ctx, key); err != nil || ok {
+		t.Errorf("Expected key to be gone, got %v (%v)", ok, err)
+	}
+	if _, err := c.Get(ctx, key); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestClient_Files(t *testing.T) {
+	server, _ := newTestServer(t)
+	c := newLoggedInClient(t, server, "alice@example.com")
+	ctx := context.Background()
+
+	hash, err := c.PutFile(ctx, []byte("print('hi')"))
+	if err != nil {
+		t.Fatalf("PutFile failed: %v", err)
+	}
+	if content, err := c.GetFile(ctx, hash); err != nil || string(content) != "print('hi')" {
+		t.Errorf("Expected file content back, got %q (%v)", content, err)
+	}
+
+	results, err := c.KeysExist(ctx, []string{FileKey(hash), FileKey("00000000"), "domain/example.com/user/bob/x"})
+	if err != nil {
+		t.Fatalf("KeysExist failed: %v", err)
+	}
+	if len(results) != 3 || !results[0].Exists || results[1].Exists || !results[2].Forbidden {
+		t.Errorf("Expected exists, missing, forbidden; got %+v", results)
+	}
+}
+
+func TestClient_Manifest(t *testing.T) {
+	server, _ := newTestServer(t)
+	c := newLoggedInClient(t, server, "alice@example.com")
+	ctx := context.Background()
+	prefix := "domain/example.com/user/alice"
+
+	c.Put(ctx, prefix+"/a", []byte("server"))
+	manifest, err := c.Manifest(ctx, prefix, []ManifestEntry{{Key: prefix + "/b", SHA256: "00"}}, true)
+	if err != nil {
+		t.Fatalf("Manifest failed: %v", err)
+	}
+	if len(manifest.Missing) != 1 || string(manifest.Missing[0].Value) != "server" {
+		t.Errorf("Expected %s/a missing with its value, got %+v", prefix, manifest.Missing)
+	}
+	if !slices.Equal(manifest.Extra, []string{prefix + "/b"}) {
+		t.Errorf("Expected %s/b extra, got %v", prefix, manifest.Extra)
+	}
+}
+
+func TestClient_Errors(t *testing.T) {
+	server, maintenanceMode := newTestServer(t)
+	ctx := context.Background()
+
+	anonymous, err := New(server.URL+"/", Options{})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	if _, err := anonymous.WhoAmI(ctx); !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("Expected ErrUnauthorized, got %v", err)
+	}
+
+	c := newLoggedInClient(t, server, "alice@example.com")
+	if _, err := c.Get(ctx, "domain/example.com/user/bob/x"); !errors.Is(err, ErrForbidden) {
+		t.Errorf("Expected ErrForbidden, got %v", err)
+	}
+
+	maintenanceMode.Set(true)
+	err = c.Put(ctx, "domain/example.com/user/alice/x", []byte("x"))
+	var apiErr *Error
+	if !errors.Is(err, ErrUnavailable) || !errors.As(err, &apiErr) {
+		t.Fatalf("Expected ErrUnavailable, got %v", err)
+	}
+	if apiErr.Message == "" || apiErr.Message[0] == '{' {
+		t.Errorf("Expected the message from the JSON envelope, got %q", apiErr.Message)
+	}
+	if status, err := c.Status(ctx); err != nil || !status.Maintenance {
+		t.Errorf("Expected maintenance status, got %+v (%v)", status, err)
+	}
+}
+
+func TestNew_InvalidBaseURL(t *testing.T) {
+	for _, baseURL := range []string{"", "trifle.example.com", "ftp://trifle.example.com", "http://[::1"} {
+		if _, err := New(baseURL, Options{}); err == nil {
+			t.Errorf("Expected New(%q) to fail", baseURL)
+		}
+	}
+}
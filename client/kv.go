@@ -0,0 +1,225 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// WhoAmI returns the email of the logged-in user
+func (c *Client) WhoAmI(ctx context.Context) (string, error) {
+	var resp struct {
+		Email string `json:"email"`
+	}
+	if err := c.doJSON(ctx, http.MethodGet, "/api/whoami", nil, nil, &resp); err != nil {
+		return "", err
+	}
+	return resp.Email, nil
+}
+
+// Status is the server's public status
+type Status struct {
+	Maintenance bool `json:"maintenance"` // writes are refused
+}
+
+// Status returns the server's public status
+func (c *Client) Status(ctx context.Context) (Status, error) {
+	var status Status
+	err := c.doJSON(ctx, http.MethodGet, "/api/status", nil, nil, &status)
+	return status, err
+}
+
+// Version describes the server build
+type Version struct {
+	Version    string `json:"version"`
+	Revision   string `json:"revision,omitempty"`
+	CommitTime string `json:"commit_time,omitempty"`
+	Modified   bool   `json:"modified,omitempty"`
+	GoVersion  string `json:"go_version"`
+	BuildDate  string `json:"build_date,omitempty"`
+}
+
+// Version returns the server's build information
+func (c *Client) Version(ctx context.Context) (Version, error) {
+	var version Version
+	err := c.doJSON(ctx, http.MethodGet, "/api/version", nil, nil, &version)
+	return version, err
+}
+
+// UserPrefix returns the key prefix holding a user's data:
+// "Alice@Example.com" -> "domain/example.com/user/alice"
+func UserPrefix(email string) (string, error) {
+	email = strings.ToLower(strings.TrimSpace(email))
+	at := strings.LastIndex(email, "@")
+	if at <= 0 || at == len(email)-1 {
+		return "", fmt.Errorf("invalid email format: %q", email)
+	}
+	return "domain/" + email[at+1:] + "/user/" + email[:at], nil
+}
+
+// Get returns the value stored at key
+func (c *Client) Get(ctx context.Context, key string) ([]byte, error) {
+	resp, err := c.do(ctx, request{method: http.MethodGet, path: "/kv/" + key})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	return io.ReadAll(resp.Body)
+}
+
+// Put stores value at key, replacing any existing value
+func (c *Client) Put(ctx context.Context, key string, value []byte) error {
+	resp, err := c.do(ctx, request{
+		method:      http.MethodPut,
+		path:        "/kv/" + key,
+		body:        bytes.NewReader(value),
+		contentType: "application/octet-stream",
+	})
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+// Delete removes key, or a prefix and everything under it
+func (c *Client) Delete(ctx context.Context, key string) error {
+	resp, err := c.do(ctx, request{method: http.MethodDelete, path: "/kv/" + key})
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+// Exists reports whether key exists
+func (c *Client) Exists(ctx context.Context, key string) (bool, error) {
+	resp, err := c.do(ctx, request{method: http.MethodHead, path: "/kv/" + key})
+	if errors.Is(err, ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	resp.Body.Close()
+	return true, nil
+}
+
+// ListOptions control List. The zero value lists one level below the prefix.
+type ListOptions struct {
+	Depth     int  // directory levels to descend; 0 means 1
+	Recursive bool // list every key under the prefix, ignoring Depth
+}
+
+// List returns the keys under prefix
+func (c *Client) List(ctx context.Context, prefix string, opts ListOptions) ([]string, error) {
+	query := url.Values{}
+	if opts.Recursive {
+		query.Set("recursive", "true")
+	} else if opts.Depth > 0 {
+		query.Set("depth", strconv.Itoa(opts.Depth))
+	}
+	var keys []string
+	err := c.doJSON(ctx, http.MethodGet, "/kvlist/"+prefix, query, nil, &keys)
+	return keys, err
+}
+
+// FileKey returns the key of a content-addressed file, as the web app
+// stores it: "file/ab/cd/abcd..." for the hex SHA-256 "abcd..."
+func FileKey(hash string) string {
+	if len(hash) < 4 {
+		return "file/" + hash
+	}
+	return "file/" + hash[:2] + "/" + hash[2:4] + "/" + hash
+}
+
+// PutFile stores content under its content-addressed key and returns its
+// hash. Storing a file that already exists is a cheap no-op on the server.
+func (c *Client) PutFile(ctx context.Context, content []byte) (string, error) {
+	sum := sha256.Sum256(content)
+	hash := hex.EncodeToString(sum[:])
+	if err := c.Put(ctx, FileKey(hash), content); err != nil {
+		return "", err
+	}
+	return hash, nil
+}
+
+// GetFile returns the content of the file with the given hash, checking
+// that it matches
+func (c *Client) GetFile(ctx context.Context, hash string) ([]byte, error) {
+	content, err := c.Get(ctx, FileKey(hash))
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(content)
+	if got := hex.EncodeToString(sum[:]); got != strings.ToLower(hash) {
+		return nil, fmt.Errorf("file %s: content hashes to %s", hash, got)
+	}
+	return content, nil
+}
+
+// ExistsResult reports on one key of KeysExist
+type ExistsResult struct {
+	Key       string `json:"key"`
+	Exists    bool   `json:"exists"`
+	Forbidden bool   `json:"forbidden,omitempty"`
+	Size      int64  `json:"size,omitempty"`
+	SHA256    string `json:"sha256,omitempty"` // empty if the server hasn't hashed it yet
+}
+
+// KeysExist reports which of up to 1000 keys exist, in the order given
+func (c *Client) KeysExist(ctx context.Context, keys []string) ([]ExistsResult, error) {
+	var results []ExistsResult
+	err := c.doJSON(ctx, http.MethodPost, "/kvexists", nil, keys, &results)
+	return results, err
+}
+
+// ManifestEntry is one key and the hex SHA-256 of its value
+type ManifestEntry struct {
+	Key    string `json:"key"`
+	SHA256 string `json:"sha256"`
+}
+
+// ServerEntry describes a value on the server. Value is only set when
+// values were requested and the value is small.
+type ServerEntry struct {
+	Key    string `json:"key"`
+	SHA256 string `json:"sha256"`
+	Size   int64  `json:"size"`
+	Value  []byte `json:"value,omitempty"`
+}
+
+// Manifest is how the client's state under a prefix differs from the server's
+type Manifest struct {
+	Missing []ServerEntry `json:"missing"` // on the server but not the client
+	Changed []ServerEntry `json:"changed"` // on both, with different values
+	Extra   []string      `json:"extra"`   // on the client but not the server
+}
+
+// Manifest compares the client's entries under prefix with the server's.
+// With includeValues, small missing and changed values are inlined.
+func (c *Client) Manifest(ctx context.Context, prefix string, entries []ManifestEntry, includeValues bool) (*Manifest, error) {
+	var query url.Values
+	if includeValues {
+		query = url.Values{"include_values": {"true"}}
+	}
+	if entries == nil {
+		entries = []ManifestEntry{}
+	}
+	req := struct {
+		Prefix  string          `json:"prefix"`
+		Entries []ManifestEntry `json:"entries"`
+	}{prefix, entries}
+
+	var manifest Manifest
+	if err := c.doJSON(ctx, http.MethodPost, "/kvsync/manifest", query, req, &manifest); err != nil {
+		return nil, err
+	}
+	return &manifest, nil
+}
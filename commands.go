@@ -0,0 +1,322 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/zellyn/trifle/internal/auth"
+	"github.com/zellyn/trifle/internal/buildinfo"
+	"github.com/zellyn/trifle/internal/config"
+	"github.com/zellyn/trifle/internal/kv"
+)
+
+const usage = `Usage: trifle <command> [flags]
+
+Commands:
+  serve                              run the web server (the default)
+  version                            print version and build information
+  allowlist list                     show allowed emails and @domains
+  allowlist add PATTERN...           allow emails or @domains to log in
+  allowlist remove PATTERN...        stop allowing emails or @domains
+  account rename -from OLD -to NEW   move a user's synced data to a new email
+  keys migrate                       move legacy user/{email}/ keys to domain/ keys
+
+Admin commands work directly on the data directory and accept -data-dir
+and -config. Run "trifle <command> -h" for details.
+`
+
+// runCommand runs an administrative subcommand and returns the exit code
+func runCommand(args []string, getenv func(string) string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return 2
+	}
+
+	var err error
+	switch args[0] {
+	case "allowlist":
+		err = runAllowlist(args[1:], getenv, stdout, stderr)
+	case "account":
+		err = runAccount(args[1:], getenv, stdout, stderr)
+	case "keys":
+		err = runKeys(args[1:], getenv, stdout, stderr)
+	case "version":
+		fmt.Fprintln(stdout, buildinfo.Get())
+		return 0
+	case "help", "-h", "-help", "--help":
+		fmt.Fprint(stdout, usage)
+		return 0
+	default:
+		fmt.Fprintf(stderr, "trifle: unknown command %q\n\n%s", args[0], usage)
+		return 2
+	}
+
+	switch {
+	case err == nil:
+		return 0
+	case errors.Is(err, flag.ErrHelp):
+		return 0
+	case errors.Is(err, errUsage):
+		return 2
+	default:
+		fmt.Fprintf(stderr, "trifle: %v\n", err)
+		return 1
+	}
+}
+
+// errUsage reports a usage error whose message has already been printed
+var errUsage = errors.New("usage error")
+
+// newCommandFlags creates a flag set whose -h output shows the command's
+// synopsis and description
+func newCommandFlags(name, synopsis, description string, stderr io.Writer) *flag.FlagSet {
+	fs := flag.NewFlagSet("trifle "+name, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: trifle %s %s\n\n%s\n\nFlags:\n", name, synopsis, description)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// usageError prints a message and the command's usage
+func usageError(fs *flag.FlagSet, format string, args ...any) error {
+	fmt.Fprintf(fs.Output(), format+"\n\n", args...)
+	fs.Usage()
+	return errUsage
+}
+
+func runAllowlist(args []string, getenv func(string) string, stdout, stderr io.Writer) error {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, "Usage: trifle allowlist list|add|remove [flags] [PATTERN...]\n")
+		return errUsage
+	}
+
+	var fs *flag.FlagSet
+	switch args[0] {
+	case "list":
+		fs = newCommandFlags("allowlist list", "[flags]",
+			"Print the patterns in the allowlist file.", stderr)
+	case "add":
+		fs = newCommandFlags("allowlist add", "[flags] PATTERN...",
+			"Allow an email (alice@example.com) or a whole domain (@example.com) to\n"+
+				"log in. Restart the server for the change to take effect.", stderr)
+	case "remove":
+		fs = newCommandFlags("allowlist remove", "[flags] PATTERN...",
+			"Remove an email or @domain from the allowlist. Existing sessions are not\n"+
+				"affected. Restart the server for the change to take effect.", stderr)
+	default:
+		fmt.Fprintf(stderr, "trifle: unknown allowlist command %q\n", args[0])
+		return errUsage
+	}
+
+	cfg, err := config.LoadCommand(fs, args[1:], getenv)
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(cfg.DataDir, "allowlist.txt")
+
+	if args[0] == "list" {
+		if fs.NArg() > 0 {
+			return usageError(fs, "unexpected arguments")
+		}
+		patterns, err := auth.ReadAllowlist(path)
+		if err != nil {
+			return err
+		}
+		for _, p := range patterns {
+			fmt.Fprintln(stdout, p)
+		}
+		return nil
+	}
+
+	if fs.NArg() == 0 {
+		return usageError(fs, "at least one PATTERN is required")
+	}
+	for _, pattern := range fs.Args() {
+		if args[0] == "add" {
+			added, err := auth.AddToAllowlist(path, pattern)
+			if err != nil {
+				return err
+			}
+			if added {
+				fmt.Fprintf(stdout, "Added %s\n", pattern)
+			} else {
+				fmt.Fprintf(stdout, "%s is already allowed\n", pattern)
+			}
+			continue
+		}
+
+		removed, err := auth.RemoveFromAllowlist(path, pattern)
+		if err != nil {
+			return err
+		}
+		if !removed {
+			return fmt.Errorf("%s is not in %s", pattern, path)
+		}
+		fmt.Fprintf(stdout, "Removed %s\n", pattern)
+	}
+	return nil
+}
+
+func runAccount(args []string, getenv func(string) string, stdout, stderr io.Writer) error {
+	if len(args) == 0 || args[0] != "rename" {
+		fmt.Fprint(stderr, "Usage: trifle account rename -from OLD -to NEW [flags]\n")
+		return errUsage
+	}
+
+	fs := newCommandFlags("account rename", "-from OLD -to NEW [flags]",
+		"Move everything synced under OLD's email to NEW's, e.g. after a user's\n"+
+			"school email changes. Fails if NEW already has synced data. The user\n"+
+			"must log in with NEW afterwards; -allow adds it to the allowlist if no\n"+
+			"pattern there covers it yet. Stop the server first: it may be writing\n"+
+			"the same keys, and nothing stops the two from racing.", stderr)
+	from := fs.String("from", "", "current email")
+	to := fs.String("to", "", "new email")
+	dryRun := fs.Bool("dry-run", false, "report what would move without changing anything")
+	allow := fs.Bool("allow", false, "add NEW to the allowlist unless it is already allowed")
+	verbose := fs.Bool("v", false, "list every key moved")
+
+	cfg, err := config.LoadCommand(fs, args[1:], getenv)
+	if err != nil {
+		return err
+	}
+	if *from == "" || *to == "" || fs.NArg() > 0 {
+		return usageError(fs, "-from and -to are required")
+	}
+
+	fromPrefix, err := kv.UserPrefix(*from)
+	if err != nil {
+		return err
+	}
+	toPrefix, err := kv.UserPrefix(*to)
+	if err != nil {
+		return err
+	}
+	if fromPrefix == toPrefix {
+		return fmt.Errorf("%s and %s map to the same data", *from, *to)
+	}
+
+	store, err := kv.NewStore(cfg.DataDir)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	keys, err := store.List(fromPrefix, 0, true)
+	if err != nil {
+		return err
+	}
+	if len(keys) == 0 {
+		return fmt.Errorf("no synced data for %s", *from)
+	}
+	if store.Exists(toPrefix) {
+		existing, _ := store.List(toPrefix, 0, true)
+		return fmt.Errorf("%s already has synced data under %s (%d keys)", *to, toPrefix, len(existing))
+	}
+
+	moved := "Moved"
+	if *dryRun {
+		moved = "Would move"
+	} else if err := store.Move(fromPrefix, toPrefix); err != nil {
+		return err
+	}
+	if *verbose {
+		for _, key := range keys {
+			fmt.Fprintf(stdout, "  %s -> %s\n", key, toPrefix+strings.TrimPrefix(key, fromPrefix))
+		}
+	}
+	fmt.Fprintf(stdout, "%s %d keys from %s to %s\n", moved, len(keys), fromPrefix, toPrefix)
+
+	if *allow {
+		return allowEmail(filepath.Join(cfg.DataDir, "allowlist.txt"), *to, *dryRun, stdout)
+	}
+	return nil
+}
+
+// allowEmail adds email to the allowlist file unless a pattern already
+// allows it
+func allowEmail(path, email string, dryRun bool, stdout io.Writer) error {
+	patterns, err := auth.ReadAllowlist(path)
+	if err != nil {
+		return err
+	}
+	switch {
+	case auth.Allows(patterns, email):
+		fmt.Fprintf(stdout, "%s is already allowed\n", email)
+	case dryRun:
+		fmt.Fprintf(stdout, "Would add %s to the allowlist\n", email)
+	default:
+		if _, err := auth.AddToAllowlist(path, email); err != nil {
+			return err
+		}
+		fmt.Fprintf(stdout, "Added %s to the allowlist; restart the server for it to take effect\n", email)
+	}
+	return nil
+}
+
+func runKeys(args []string, getenv func(string) string, stdout, stderr io.Writer) error {
+	if len(args) == 0 || args[0] != "migrate" {
+		fmt.Fprint(stderr, "Usage: trifle keys migrate [flags]\n")
+		return errUsage
+	}
+
+	fs := newCommandFlags("keys migrate", "[flags]",
+		"Move every key under the legacy user/{email}/ layout to\n"+
+			"domain/{domain}/user/{localpart}/. Each value is copied and verified\n"+
+			"before the legacy key is deleted. Keys whose email can't be parsed,\n"+
+			"or whose new key already holds a different value, are left in place.\n"+
+			"Once nothing is left, start the server with -legacy-keys=false.", stderr)
+	dryRun := fs.Bool("dry-run", false, "report what would move without changing anything")
+
+	cfg, err := config.LoadCommand(fs, args[1:], getenv)
+	if err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return usageError(fs, "unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+
+	store, err := kv.NewStore(cfg.DataDir)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	report, err := store.MigrateLegacyKeys(*dryRun)
+	if err != nil {
+		return err
+	}
+
+	moved, removed := "Moved", "Removed"
+	if *dryRun {
+		moved, removed = "Would move", "Would remove"
+	}
+	for _, m := range report.Moved {
+		fmt.Fprintf(stdout, "%s %s -> %s\n", moved, m.From, m.To)
+	}
+	for _, m := range report.Duplicates {
+		fmt.Fprintf(stdout, "%s %s (already at %s)\n", removed, m.From, m.To)
+	}
+	for _, m := range report.Conflicts {
+		fmt.Fprintf(stdout, "Skipped %s: %s holds a different value\n", m.From, m.To)
+	}
+	for _, key := range report.Invalid {
+		fmt.Fprintf(stdout, "Skipped %s: no valid email in key\n", key)
+	}
+	for _, m := range report.Failed {
+		fmt.Fprintf(stdout, "Failed %s -> %s: %v\n", m.From, m.To, m.Err)
+	}
+	fmt.Fprintf(stdout, "%s %d keys, %s %d duplicates; skipped %d, failed %d\n",
+		moved, len(report.Moved), strings.ToLower(removed), len(report.Duplicates),
+		len(report.Conflicts)+len(report.Invalid), len(report.Failed))
+
+	if len(report.Failed) > 0 {
+		return fmt.Errorf("%d keys failed to migrate", len(report.Failed))
+	}
+	return nil
+}
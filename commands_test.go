@@ -0,0 +1,233 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/zellyn/trifle/internal/kv"
+)
+
+// runTestCommand runs an admin command against dataDir and returns its
+// exit code and output
+func runTestCommand(t *testing.T, dataDir string, args ...string) (int, string, string) {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	getenv := func(key string) string {
+		if key == "DATA_DIR" {
+			return dataDir
+		}
+		return ""
+	}
+	code := runCommand(args, getenv, &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func TestCommand_Allowlist(t *testing.T) {
+	dataDir := t.TempDir()
+	path := filepath.Join(dataDir, "allowlist.txt")
+	if err := os.WriteFile(path, []byte("# Teachers\nbob@example.com\n@school.edu\n"), 0644); err != nil {
+		t.Fatalf("Failed to write allowlist: %v", err)
+	}
+
+	code, out, _ := runTestCommand(t, dataDir, "allowlist", "add", "Alice@Example.com", "@school.edu")
+	if code != 0 {
+		t.Fatalf("add exited %d", code)
+	}
+	if !strings.Contains(out, "Added Alice@Example.com") || !strings.Contains(out, "@school.edu is already allowed") {
+		t.Errorf("Unexpected add output: %q", out)
+	}
+
+	code, _, _ = runTestCommand(t, dataDir, "allowlist", "remove", "BOB@example.com")
+	if code != 0 {
+		t.Fatalf("remove exited %d", code)
+	}
+
+	code, out, _ = runTestCommand(t, dataDir, "allowlist", "list")
+	if code != 0 {
+		t.Fatalf("list exited %d", code)
+	}
+	if want := "@school.edu\nalice@example.com\n"; out != want {
+		t.Errorf("list = %q, want %q", out, want)
+	}
+
+	data, _ := os.ReadFile(path)
+	if !strings.HasPrefix(string(data), "# Teachers\n") {
+		t.Errorf("Expected comments to survive edits, got %q", data)
+	}
+
+	if code, _, stderr := runTestCommand(t, dataDir, "allowlist", "remove", "nobody@example.com"); code != 1 || !strings.Contains(stderr, "not in") {
+		t.Errorf("Expected removing a missing pattern to fail, got %d %q", code, stderr)
+	}
+	if code, _, _ := runTestCommand(t, dataDir, "allowlist", "add", "not an email"); code != 1 {
+		t.Errorf("Expected invalid pattern to fail, got %d", code)
+	}
+}
+
+func TestCommand_AllowlistCreatesDefaults(t *testing.T) {
+	dataDir := t.TempDir()
+	if code, _, _ := runTestCommand(t, dataDir, "allowlist", "add", "@example.org"); code != 0 {
+		t.Fatalf("add exited %d", code)
+	}
+	_, out, _ := runTestCommand(t, dataDir, "allowlist", "list")
+	if !strings.Contains(out, "zellyn@gmail.com") || !strings.Contains(out, "@example.org") {
+		t.Errorf("Expected defaults plus the new pattern, got %q", out)
+	}
+}
+
+func TestCommand_AccountRename(t *testing.T) {
+	dataDir := t.TempDir()
+	store, err := kv.NewStore(dataDir)
+	if err != nil {
+		t.Fatalf("NewStore failed: %v", err)
+	}
+	for _, key := range []string{
+		"domain/old.edu/user/alice/profile",
+		"domain/old.edu/user/alice/trifle/version/version_abc",
+		"domain/new.edu/user/carol/profile",
+	} {
+		if err := store.Put(key, []byte("x")); err != nil {
+			t.Fatalf("Put failed: %v", err)
+		}
+	}
+
+	code, out, _ := runTestCommand(t, dataDir, "account", "rename", "-from", "Alice@old.edu", "-to", "alice@new.edu", "-dry-run")
+	if code != 0 || !strings.Contains(out, "Would move 2 keys") {
+		t.Fatalf("Unexpected dry run result %d %q", code, out)
+	}
+	if !store.Exists("domain/old.edu/user/alice/profile") {
+		t.Fatalf("Dry run moved data")
+	}
+
+	code, _, stderr := runTestCommand(t, dataDir, "account", "rename", "-from", "alice@old.edu", "-to", "carol@new.edu")
+	if code != 1 || !strings.Contains(stderr, "already has synced data") {
+		t.Errorf("Expected collision to fail, got %d %q", code, stderr)
+	}
+
+	code, out, _ = runTestCommand(t, dataDir, "account", "rename", "-from", "alice@old.edu", "-to", "alice@new.edu")
+	if code != 0 || !strings.Contains(out, "Moved 2 keys") {
+		t.Fatalf("Unexpected rename result %d %q", code, out)
+	}
+	if store.Exists("domain/old.edu/user/alice/profile") || !store.Exists("domain/new.edu/user/alice/trifle/version/version_abc") {
+		t.Errorf("Expected data to move to the new prefix")
+	}
+
+	if code, _, _ := runTestCommand(t, dataDir, "account", "rename", "-from", "nobody@old.edu", "-to", "x@new.edu"); code != 1 {
+		t.Errorf("Expected renaming a user with no data to fail, got %d", code)
+	}
+	if code, _, _ := runTestCommand(t, dataDir, "account", "rename", "-from", "alice@new.edu"); code != 2 {
+		t.Errorf("Expected missing -to to be a usage error, got %d", code)
+	}
+}
+
+func TestCommand_AccountRenameAllow(t *testing.T) {
+	dataDir := t.TempDir()
+	store, err := kv.NewStore(dataDir)
+	if err != nil {
+		t.Fatalf("NewStore failed: %v", err)
+	}
+	if err := store.Put("domain/old.edu/user/alice/profile", []byte("x")); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	allowlist := filepath.Join(dataDir, "allowlist.txt")
+	if err := os.WriteFile(allowlist, []byte("@old.edu\n@covered.edu\n"), 0644); err != nil {
+		t.Fatalf("Failed to write allowlist: %v", err)
+	}
+
+	code, out, _ := runTestCommand(t, dataDir, "account", "rename", "-from", "alice@old.edu", "-to", "alice@new.edu", "-allow", "-v", "-dry-run")
+	if code != 0 || !strings.Contains(out, "domain/old.edu/user/alice/profile -> domain/new.edu/user/alice/profile") ||
+		!strings.Contains(out, "Would add alice@new.edu") {
+		t.Fatalf("Unexpected dry run result %d %q", code, out)
+	}
+	if data, _ := os.ReadFile(allowlist); strings.Contains(string(data), "alice@new.edu") {
+		t.Fatalf("Dry run changed the allowlist")
+	}
+
+	code, out, _ = runTestCommand(t, dataDir, "account", "rename", "-from", "alice@old.edu", "-to", "alice@new.edu", "-allow")
+	if code != 0 || !strings.Contains(out, "Added alice@new.edu") {
+		t.Fatalf("Unexpected rename result %d %q", code, out)
+	}
+	if data, _ := os.ReadFile(allowlist); !strings.Contains(string(data), "alice@new.edu\n") {
+		t.Errorf("Expected alice@new.edu in the allowlist, got %q", data)
+	}
+
+	code, out, _ = runTestCommand(t, dataDir, "account", "rename", "-from", "alice@new.edu", "-to", "alice@covered.edu", "-allow")
+	if code != 0 || !strings.Contains(out, "alice@covered.edu is already allowed") {
+		t.Errorf("Expected a domain pattern to cover the new email, got %d %q", code, out)
+	}
+}
+
+func TestCommand_KeysMigrate(t *testing.T) {
+	dataDir := t.TempDir()
+	store, err := kv.NewStore(dataDir)
+	if err != nil {
+		t.Fatalf("NewStore failed: %v", err)
+	}
+	for key, value := range map[string]string{
+		"user/Alice@Example.com/profile":          "alice",
+		"user/bob@example.com/profile":            "bob-old",
+		"domain/example.com/user/bob/profile":     "bob-new",
+		"user/nobody/profile":                     "x",
+		"user/carol+test@example.com/trifle/t1/v": "carol",
+	} {
+		if err := store.Put(key, []byte(value)); err != nil {
+			t.Fatalf("Put failed: %v", err)
+		}
+	}
+
+	code, out, _ := runTestCommand(t, dataDir, "keys", "migrate", "-dry-run")
+	if code != 0 || !strings.Contains(out, "Would move 2 keys") {
+		t.Fatalf("Unexpected dry run result %d %q", code, out)
+	}
+	if !store.Exists("user/Alice@Example.com/profile") {
+		t.Fatalf("Dry run moved data")
+	}
+
+	code, out, _ = runTestCommand(t, dataDir, "keys", "migrate")
+	if code != 0 || !strings.Contains(out, "Moved 2 keys") || !strings.Contains(out, "skipped 2") {
+		t.Fatalf("Unexpected migrate result %d %q", code, out)
+	}
+	if !store.Exists("domain/example.com/user/alice/profile") || !store.Exists("domain/example.com/user/carol+test/trifle/t1/v") {
+		t.Errorf("Expected data to move to the domain/ layout")
+	}
+	if !store.Exists("user/bob@example.com/profile") || !store.Exists("user/nobody/profile") {
+		t.Errorf("Expected skipped keys to be kept")
+	}
+
+	if code, _, _ := runTestCommand(t, dataDir, "keys", "rename"); code != 2 {
+		t.Errorf("Expected unknown keys subcommand to be a usage error, got %d", code)
+	}
+}
+
+func TestCommand_Help(t *testing.T) {
+	for _, args := range [][]string{
+		{"help"},
+		{"allowlist", "add", "-h"},
+		{"allowlist", "list", "-h"},
+		{"account", "rename", "-h"},
+	} {
+		code, stdout, stderr := runTestCommand(t, t.TempDir(), args...)
+		if code != 0 {
+			t.Errorf("%v exited %d", args, code)
+		}
+		if !strings.Contains(stdout+stderr, "Usage: trifle") {
+			t.Errorf("%v: expected usage text, got %q", args, stdout+stderr)
+		}
+	}
+
+	if code, _, _ := runTestCommand(t, t.TempDir(), "frobnicate"); code != 2 {
+		t.Errorf("Expected unknown command to exit 2, got %d", code)
+	}
+}
+
+func TestCommand_Version(t *testing.T) {
+	code, stdout, _ := runTestCommand(t, t.TempDir(), "version")
+	if code != 0 {
+		t.Errorf("Expected exit 0, got %d", code)
+	}
+	if !strings.HasPrefix(stdout, "trifle ") || !strings.Contains(stdout, "built with go") {
+		t.Errorf("Expected version output, got %q", stdout)
+	}
+}
@@ -0,0 +1,354 @@
+// Package apidoc builds an OpenAPI 3 description of the HTTP API from a
+// table of operations that reference the handlers' real request and
+// response types, so the document can't drift from the Go structs.
+package apidoc
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrorResponse is the JSON error envelope returned by the API fallback
+// (unknown paths) and by maintenance mode. Most handlers return plain-text
+// errors instead.
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
+
+// Auth says how an operation is authorized
+type Auth int
+
+const (
+	AuthNone    Auth = iota // public
+	AuthSession             // logged-in session cookie
+	AuthAdmin               // admin session cookie or admin bearer token
+)
+
+// Raw marks a request or response body of arbitrary bytes rather than JSON
+type Raw struct{}
+
+// Param describes a query parameter. Path parameters are derived from the
+// {braces} in Operation.Path.
+type Param struct {
+	Name        string
+	Type        string // "string", "integer", or "boolean"
+	Description string
+}
+
+// Operation describes one method on one path
+type Operation struct {
+	Method      string
+	Path        string // e.g. "/kv/{key}"
+	Summary     string
+	Description string
+	Auth        Auth
+	Query       []Param
+	Request     any   // zero value of the request body type, or nil
+	Response    any   // zero value of the success body type, or nil
+	Status      int   // success status; 0 means 200
+	Errors      []int // documented error statuses
+	MessagePack bool  // bodies may also be application/msgpack
+}
+
+// Spec is a set of operations
+type Spec struct {
+	Title      string
+	Version    string
+	Operations []Operation
+}
+
+// Document builds the OpenAPI 3 document
+func (s *Spec) Document() (map[string]any, error) {
+	b := &builder{schemas: map[string]any{}, types: map[string]reflect.Type{}}
+
+	paths := map[string]any{}
+	for _, op := range s.Operations {
+		item, _ := paths[op.Path].(map[string]any)
+		if item == nil {
+			item = map[string]any{}
+			paths[op.Path] = item
+		}
+		method := strings.ToLower(op.Method)
+		if _, ok := item[method]; ok {
+			return nil, fmt.Errorf("duplicate operation %s %s", op.Method, op.Path)
+		}
+		doc, err := b.operation(op)
+		if err != nil {
+			return nil, fmt.Errorf("%s %s: %w", op.Method, op.Path, err)
+		}
+		item[method] = doc
+	}
+
+	if _, err := b.schema(reflect.TypeFor[ErrorResponse]()); err != nil {
+		return nil, err
+	}
+
+	return map[string]any{
+		"openapi": "3.0.3",
+		"info": map[string]any{
+			"title":   s.Title,
+			"version": s.Version,
+		},
+		"paths": paths,
+		"components": map[string]any{
+			"schemas": b.schemas,
+			"securitySchemes": map[string]any{
+				"cookieAuth": map[string]any{
+					"type": "apiKey",
+					"in":   "cookie",
+					"name": "trifle_session",
+				},
+				"bearerAuth": map[string]any{
+					"type":        "http",
+					"scheme":      "bearer",
+					"description": "The server's admin token; admin endpoints only",
+				},
+			},
+		},
+	}, nil
+}
+
+// Handler serves the document as JSON. The document is built once, so an
+// invalid spec fails at startup rather than on first request.
+func (s *Spec) Handler() (http.HandlerFunc, error) {
+	doc, err := s.Document()
+	if err != nil {
+		return nil, err
+	}
+	body, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode OpenAPI document: %w", err)
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Write(body)
+	}, nil
+}
+
+type builder struct {
+	schemas map[string]any
+	types   map[string]reflect.Type
+}
+
+func (b *builder) operation(op Operation) (map[string]any, error) {
+	doc := map[string]any{"summary": op.Summary}
+	if op.Description != "" {
+		doc["description"] = op.Description
+	}
+
+	var params []any
+	for _, name := range pathParams(op.Path) {
+		params = append(params, map[string]any{
+			"name":     name,
+			"in":       "path",
+			"required": true,
+			"schema":   map[string]any{"type": "string"},
+		})
+	}
+	for _, p := range op.Query {
+		params = append(params, map[string]any{
+			"name":        p.Name,
+			"in":          "query",
+			"description": p.Description,
+			"schema":      map[string]any{"type": p.Type},
+		})
+	}
+	if params != nil {
+		doc["parameters"] = params
+	}
+
+	if op.Request != nil {
+		content, err := b.content(op.Request, op.MessagePack)
+		if err != nil {
+			return nil, err
+		}
+		doc["requestBody"] = map[string]any{"required": true, "content": content}
+	}
+
+	status := op.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	success := map[string]any{"description": http.StatusText(status)}
+	if op.Response != nil {
+		content, err := b.content(op.Response, op.MessagePack)
+		if err != nil {
+			return nil, err
+		}
+		success["content"] = content
+	}
+	responses := map[string]any{strconv.Itoa(status): success}
+	for _, code := range op.Errors {
+		responses[strconv.Itoa(code)] = errorResponse(code)
+	}
+	doc["responses"] = responses
+
+	switch op.Auth {
+	case AuthSession:
+		doc["security"] = []any{map[string]any{"cookieAuth": []string{}}}
+	case AuthAdmin:
+		doc["security"] = []any{
+			map[string]any{"cookieAuth": []string{}},
+			map[string]any{"bearerAuth": []string{}},
+		}
+	}
+	return doc, nil
+}
+
+func (b *builder) content(v any, msgpack bool) (map[string]any, error) {
+	if _, ok := v.(Raw); ok {
+		return map[string]any{
+			"application/octet-stream": map[string]any{
+				"schema": map[string]any{"type": "string", "format": "binary"},
+			},
+		}, nil
+	}
+	schema, err := b.schema(reflect.TypeOf(v))
+	if err != nil {
+		return nil, err
+	}
+	content := map[string]any{"application/json": map[string]any{"schema": schema}}
+	if msgpack {
+		content["application/msgpack"] = map[string]any{"schema": schema}
+	}
+	return content, nil
+}
+
+// errorResponse documents an error status. 404s from the API fallback and
+// 503s from maintenance mode use the JSON envelope; the rest are text.
+func errorResponse(code int) map[string]any {
+	resp := map[string]any{"description": http.StatusText(code)}
+	switch code {
+	case http.StatusNotFound, http.StatusServiceUnavailable:
+		resp["content"] = map[string]any{
+			"application/json": map[string]any{"schema": ref("ErrorResponse")},
+			"text/plain":       map[string]any{"schema": map[string]any{"type": "string"}},
+		}
+	default:
+		resp["content"] = map[string]any{
+			"text/plain": map[string]any{"schema": map[string]any{"type": "string"}},
+		}
+	}
+	return resp
+}
+
+// schema returns the JSON schema for t, registering named structs as
+// components and referring to them by name
+func (b *builder) schema(t reflect.Type) (map[string]any, error) {
+	switch t {
+	case reflect.TypeFor[time.Time]():
+		return map[string]any{"type": "string", "format": "date-time"}, nil
+	case reflect.TypeFor[[]byte]():
+		return map[string]any{"type": "string", "format": "byte"}, nil
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return b.schema(t.Elem())
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}, nil
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}, nil
+	case reflect.String:
+		return map[string]any{"type": "string"}, nil
+	case reflect.Interface:
+		return map[string]any{}, nil
+	case reflect.Slice, reflect.Array:
+		items, err := b.schema(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return map[string]any{"type": "array", "items": items}, nil
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("unsupported map key type %s", t.Key())
+		}
+		values, err := b.schema(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return map[string]any{"type": "object", "additionalProperties": values}, nil
+	case reflect.Struct:
+		if t.Name() == "" {
+			return b.object(t)
+		}
+		name := t.Name()
+		if seen, ok := b.types[name]; ok {
+			if seen != t {
+				return nil, fmt.Errorf("schema name %s used by both %s and %s", name, seen, t)
+			}
+			return ref(name), nil
+		}
+		b.types[name] = t
+		obj, err := b.object(t)
+		if err != nil {
+			return nil, err
+		}
+		b.schemas[name] = obj
+		return ref(name), nil
+	}
+	return nil, fmt.Errorf("unsupported type %s", t)
+}
+
+// object builds the schema for a struct's JSON fields
+func (b *builder) object(t reflect.Type) (map[string]any, error) {
+	props := map[string]any{}
+	var required []string
+	for i := range t.NumField() {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name, opts, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" && opts == "" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		schema, err := b.schema(f.Type)
+		if err != nil {
+			return nil, fmt.Errorf("field %s.%s: %w", t.Name(), f.Name, err)
+		}
+		props[name] = schema
+		if !slices.Contains(strings.Split(opts, ","), "omitempty") {
+			required = append(required, name)
+		}
+	}
+	obj := map[string]any{"type": "object", "properties": props}
+	if required != nil {
+		obj["required"] = required
+	}
+	return obj, nil
+}
+
+func ref(name string) map[string]any {
+	return map[string]any{"$ref": "#/components/schemas/" + name}
+}
+
+// pathParams returns the {names} in an OpenAPI path
+func pathParams(path string) []string {
+	var names []string
+	for {
+		start := strings.Index(path, "{")
+		if start < 0 {
+			return names
+		}
+		end := strings.Index(path[start:], "}")
+		if end < 0 {
+			return names
+		}
+		names = append(names, path[start+1:start+end])
+		path = path[start+end+1:]
+	}
+}
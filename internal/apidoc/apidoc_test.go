@@ -0,0 +1,167 @@
+package apidoc
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+type widget struct {
+	Name    string            `json:"name"`
+	Count   int               `json:"count,omitempty"`
+	Tags    []string          `json:"tags"`
+	Labels  map[string]string `json:"labels,omitempty"`
+	Data    []byte            `json:"data,omitempty"`
+	Created time.Time         `json:"created"`
+	Part    *part             `json:"part,omitempty"`
+	Skipped string            `json:"-"`
+	hidden  string
+}
+
+type part struct {
+	ID string `json:"id"`
+}
+
+func testSpec() *Spec {
+	return &Spec{
+		Title:   "Test",
+		Version: "v1",
+		Operations: []Operation{
+			{Method: http.MethodGet, Path: "/widgets/{id}", Summary: "Get", Auth: AuthSession, Response: widget{}, Errors: []int{404}},
+			{Method: http.MethodPut, Path: "/widgets/{id}", Summary: "Put", Auth: AuthAdmin, Request: Raw{}, Status: 204},
+			{Method: http.MethodGet, Path: "/status", Summary: "Status", Query: []Param{{Name: "verbose", Type: "boolean"}}},
+		},
+	}
+}
+
+// lookup follows a path of keys through a decoded JSON document
+func lookup(t *testing.T, doc any, keys ...string) any {
+	t.Helper()
+	for _, k := range keys {
+		m, ok := doc.(map[string]any)
+		if !ok {
+			t.Fatalf("Expected an object at %q in %v", k, keys)
+		}
+		doc = m[k]
+	}
+	return doc
+}
+
+func TestSpec_Handler(t *testing.T) {
+	handler, err := testSpec().Handler()
+	if err != nil {
+		t.Fatalf("Handler failed: %v", err)
+	}
+	rec := httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodGet, "/api/openapi.json", nil))
+
+	var doc any
+	if err := json.NewDecoder(rec.Body).Decode(&doc); err != nil {
+		t.Fatalf("Failed to decode document: %v", err)
+	}
+
+	if got := lookup(t, doc, "openapi"); got != "3.0.3" {
+		t.Errorf("Expected openapi 3.0.3, got %v", got)
+	}
+	get := lookup(t, doc, "paths", "/widgets/{id}", "get")
+	if got := lookup(t, get, "responses", "200", "content", "application/json", "schema", "$ref"); got != "#/components/schemas/widget" {
+		t.Errorf("Expected widget $ref, got %v", got)
+	}
+	if got := lookup(t, get, "responses", "404", "content", "application/json", "schema", "$ref"); got != "#/components/schemas/ErrorResponse" {
+		t.Errorf("Expected ErrorResponse for 404, got %v", got)
+	}
+	if params := lookup(t, get, "parameters").([]any); len(params) != 1 || lookup(t, params[0], "in") != "path" {
+		t.Errorf("Expected one path parameter, got %v", params)
+	}
+	if got := lookup(t, get, "security").([]any); len(got) != 1 || lookup(t, got[0], "cookieAuth") == nil {
+		t.Errorf("Expected cookie auth, got %v", got)
+	}
+
+	put := lookup(t, doc, "paths", "/widgets/{id}", "put")
+	if got := lookup(t, put, "requestBody", "content", "application/octet-stream", "schema", "format"); got != "binary" {
+		t.Errorf("Expected a binary request body, got %v", got)
+	}
+	if got := lookup(t, put, "responses", "204"); got == nil {
+		t.Error("Expected a 204 response")
+	}
+	if got := lookup(t, put, "security").([]any); len(got) != 2 {
+		t.Errorf("Expected cookie or bearer auth, got %v", got)
+	}
+
+	if got := lookup(t, doc, "paths", "/status", "get", "security"); got != nil {
+		t.Errorf("Expected a public operation, got security %v", got)
+	}
+	for _, scheme := range []string{"cookieAuth", "bearerAuth"} {
+		if lookup(t, doc, "components", "securitySchemes", scheme) == nil {
+			t.Errorf("Expected security scheme %s", scheme)
+		}
+	}
+}
+
+func TestSchema_Struct(t *testing.T) {
+	doc, err := testSpec().Document()
+	if err != nil {
+		t.Fatalf("Document failed: %v", err)
+	}
+	w := lookup(t, doc, "components", "schemas", "widget").(map[string]any)
+	props := w["properties"].(map[string]any)
+
+	tests := []struct {
+		field, key string
+		want       any
+	}{
+		{"name", "type", "string"},
+		{"count", "type", "integer"},
+		{"tags", "type", "array"},
+		{"labels", "type", "object"},
+		{"data", "format", "byte"},
+		{"created", "format", "date-time"},
+		{"part", "$ref", "#/components/schemas/part"},
+	}
+	for _, tt := range tests {
+		if got := lookup(t, props, tt.field, tt.key); got != tt.want {
+			t.Errorf("Field %s: expected %s %v, got %v", tt.field, tt.key, tt.want, got)
+		}
+	}
+	for _, name := range []string{"Skipped", "-", "hidden"} {
+		if _, ok := props[name]; ok {
+			t.Errorf("Expected field %s to be left out", name)
+		}
+	}
+
+	required := w["required"].([]string)
+	if !slices.Equal(required, []string{"name", "tags", "created"}) {
+		t.Errorf("Expected required [name tags created], got %v", required)
+	}
+}
+
+func TestDocument_Errors(t *testing.T) {
+	type unsupported struct {
+		C chan int `json:"c"`
+	}
+	tests := []struct {
+		name string
+		ops  []Operation
+		want string
+	}{
+		{"duplicate", []Operation{
+			{Method: "GET", Path: "/a", Summary: "one"},
+			{Method: "GET", Path: "/a", Summary: "two"},
+		}, "duplicate operation"},
+		{"unsupported type", []Operation{
+			{Method: "GET", Path: "/a", Response: unsupported{}},
+		}, "unsupported type"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := (&Spec{Operations: tt.ops}).Document()
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
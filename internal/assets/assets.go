@@ -0,0 +1,273 @@
+// Package assets serves embedded static files with cache-friendly headers.
+//
+// At startup every file is hashed. Files other than HTML pages and the
+// service worker are also exposed under a fingerprinted name containing
+// the hash (e.g. /js/app.3fa9d2ab.js), served as immutable for a year.
+// Plain names are served with "Cache-Control: no-cache" and an ETag, so
+// browsers revalidate cheaply and always see the current deploy.
+//
+// HTML pages may reference assets with the "asset" template function, e.g.
+// <script src="{{asset "/js/app.js"}}">, which is expanded once at startup.
+// ES module imports inside the JS files still use plain names, so only entry
+// points referenced this way (or via the manifest) get long-term caching.
+package assets
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// fingerprintLen is the number of hex digits of the hash used in fingerprinted names
+const fingerprintLen = 8
+
+const (
+	immutableCacheControl  = "public, max-age=31536000, immutable"
+	revalidateCacheControl = "no-cache"
+)
+
+// Asset describes one embedded file
+type Asset struct {
+	Path        string // URL path, e.g. "/js/app.js"
+	Hash        string // hex SHA-256 of the contents
+	Size        int64
+	Fingerprint string // fingerprinted URL path, or "" if not fingerprinted
+	content     []byte
+}
+
+// Assets holds the hashed contents of a static file tree
+type Assets struct {
+	byPath        map[string]*Asset
+	byFingerprint map[string]*Asset
+	version       string // see Manifest.Version
+	manifest      []byte // encoded Manifest
+}
+
+// File is content generated at startup, served and cached like the
+// embedded files
+type File struct {
+	Path    string // URL path, e.g. "/js/words.js"
+	Content []byte
+}
+
+// New hashes every file in fsys, plus any generated files, which replace
+// embedded files at the same path. HTML pages that use the "asset" template
+// function are rendered after all other files have been hashed.
+func New(fsys fs.FS, generated ...File) (*Assets, error) {
+	a := &Assets{
+		byPath:        make(map[string]*Asset),
+		byFingerprint: make(map[string]*Asset),
+	}
+
+	var pages []string
+	err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+
+		content, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", name, err)
+		}
+		if strings.HasSuffix(name, ".html") && strings.Contains(string(content), "{{asset ") {
+			pages = append(pages, name)
+			return nil
+		}
+		a.add("/"+name, content)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load assets: %w", err)
+	}
+	for _, f := range generated {
+		a.add(f.Path, f.Content)
+	}
+
+	for _, name := range pages {
+		content, err := a.render(fsys, name)
+		if err != nil {
+			return nil, err
+		}
+		a.add("/"+name, content)
+	}
+
+	a.version = a.computeVersion()
+	a.manifest, err = json.Marshal(a.Manifest())
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode asset manifest: %w", err)
+	}
+	return a, nil
+}
+
+// add hashes content and registers it under urlPath
+func (a *Assets) add(urlPath string, content []byte) {
+	sum := sha256.Sum256(content)
+	asset := &Asset{
+		Path:    urlPath,
+		Hash:    hex.EncodeToString(sum[:]),
+		Size:    int64(len(content)),
+		content: content,
+	}
+	if shouldFingerprint(urlPath) {
+		asset.Fingerprint = fingerprint(urlPath, asset.Hash)
+		a.byFingerprint[asset.Fingerprint] = asset
+	}
+	a.byPath[urlPath] = asset
+}
+
+// render expands asset references in an HTML page
+func (a *Assets) render(fsys fs.FS, name string) ([]byte, error) {
+	tmpl, err := template.New(path.Base(name)).Funcs(a.FuncMap()).ParseFS(fsys, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", name, err)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, nil); err != nil {
+		return nil, fmt.Errorf("failed to render %s: %w", name, err)
+	}
+	return buf.Bytes(), nil
+}
+
+// shouldFingerprint reports whether a file gets a fingerprinted alias.
+// Pages keep stable URLs, and the service worker must stay at its
+// registered URL.
+func shouldFingerprint(urlPath string) bool {
+	return !strings.HasSuffix(urlPath, ".html") && urlPath != "/sw.js"
+}
+
+// fingerprint inserts the short hash before the extension:
+// "/js/app.js" -> "/js/app.3fa9d2ab.js"
+func fingerprint(urlPath, hash string) string {
+	ext := path.Ext(urlPath)
+	return strings.TrimSuffix(urlPath, ext) + "." + hash[:fingerprintLen] + ext
+}
+
+// Path returns the fingerprinted URL for an asset, or urlPath unchanged if
+// the asset is unknown or not fingerprinted
+func (a *Assets) Path(urlPath string) string {
+	if asset, ok := a.byPath[urlPath]; ok && asset.Fingerprint != "" {
+		return asset.Fingerprint
+	}
+	return urlPath
+}
+
+// FuncMap provides an "asset" template function that maps plain asset
+// paths to fingerprinted ones, e.g. {{asset "/js/app.js"}}. Unknown paths
+// are an error, so typos fail at startup rather than in the browser.
+func (a *Assets) FuncMap() template.FuncMap {
+	return template.FuncMap{
+		"asset": func(urlPath string) (string, error) {
+			if _, ok := a.byPath[urlPath]; !ok {
+				return "", fmt.Errorf("unknown asset %q", urlPath)
+			}
+			return a.Path(urlPath), nil
+		},
+	}
+}
+
+// Lookup returns the asset for a plain or fingerprinted URL path
+func (a *Assets) Lookup(urlPath string) (*Asset, bool) {
+	if asset, ok := a.byFingerprint[urlPath]; ok {
+		return asset, true
+	}
+	asset, ok := a.byPath[urlPath]
+	return asset, ok
+}
+
+// List returns all assets sorted by path
+func (a *Assets) List() []*Asset {
+	list := make([]*Asset, 0, len(a.byPath))
+	for _, asset := range a.byPath {
+		list = append(list, asset)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Path < list[j].Path })
+	return list
+}
+
+// ManifestEntry describes one asset in the manifest
+type ManifestEntry struct {
+	URL  string `json:"url"`  // fingerprinted URL if there is one, else the plain path
+	Hash string `json:"hash"` // hex SHA-256 of the contents as served
+	Size int64  `json:"size"`
+}
+
+// Manifest is the JSON document listing every asset by plain path, for
+// clients such as the service worker that precache the app
+type Manifest struct {
+	// Version changes whenever any asset is added, removed, or changed,
+	// so it can name a cache
+	Version string                   `json:"version"`
+	Assets  map[string]ManifestEntry `json:"assets"`
+}
+
+// Manifest builds the asset manifest
+func (a *Assets) Manifest() Manifest {
+	m := Manifest{Version: a.version, Assets: make(map[string]ManifestEntry, len(a.byPath))}
+	for _, asset := range a.byPath {
+		m.Assets[asset.Path] = ManifestEntry{URL: a.Path(asset.Path), Hash: asset.Hash, Size: asset.Size}
+	}
+	return m
+}
+
+// computeVersion hashes every asset's path and hash
+func (a *Assets) computeVersion() string {
+	h := sha256.New()
+	for _, asset := range a.List() {
+		fmt.Fprintf(h, "%s %s\n", asset.Path, asset.Hash)
+	}
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+// HandleManifest serves the asset manifest as JSON. It changes with every
+// deploy, so it is never cached without revalidation; its ETag is the
+// version, so revalidating an unchanged manifest is a 304.
+func (a *Assets) HandleManifest(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", revalidateCacheControl)
+	w.Header().Set("ETag", `"`+a.version+`"`)
+	http.ServeContent(w, r, "asset-manifest.json", time.Time{}, bytes.NewReader(a.manifest))
+}
+
+// ServeHTTP serves an asset by plain or fingerprinted path. Directory
+// paths serve their index.html.
+func (a *Assets) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	urlPath := r.URL.Path
+	if strings.HasSuffix(urlPath, "/") {
+		urlPath += "index.html"
+	}
+
+	asset, ok := a.Lookup(urlPath)
+	if !ok {
+		a.NotFound(w, r)
+		return
+	}
+
+	cacheControl := revalidateCacheControl
+	if urlPath == asset.Fingerprint {
+		cacheControl = immutableCacheControl
+	}
+	serve(w, r, asset, cacheControl)
+}
+
+// serve writes an asset with the given Cache-Control and an ETag derived
+// from its hash
+func serve(w http.ResponseWriter, r *http.Request, asset *Asset, cacheControl string) {
+	w.Header().Set("Cache-Control", cacheControl)
+	w.Header().Set("ETag", `"`+asset.Hash[:16]+`"`)
+
+	// ServeContent handles If-None-Match, Range, HEAD, and the Content-Type
+	// (from the plain file name's extension)
+	http.ServeContent(w, r, asset.Path, time.Time{}, bytes.NewReader(asset.content))
+}
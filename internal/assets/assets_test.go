@@ -0,0 +1,273 @@
+package assets
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func testFS() fstest.MapFS {
+	return fstest.MapFS{
+		"index.html":  {Data: []byte(`<link rel="stylesheet" href="{{asset "/css/app.css"}}"><script src="{{asset "/js/app.js"}}"></script>`)},
+		"about.html":  {Data: []byte(`<p>About</p>`)},
+		"sw.js":       {Data: []byte(`self.addEventListener('fetch', () => {});`)},
+		"css/app.css": {Data: []byte(`body { margin: 0; }`)},
+		"js/app.js":   {Data: []byte(`console.log("app");`)},
+	}
+}
+
+func newTestAssets(t *testing.T) *Assets {
+	t.Helper()
+	a, err := New(testFS())
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	return a
+}
+
+func get(a *Assets, path string, header http.Header) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	rec := httptest.NewRecorder()
+	a.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestNew_HashStability(t *testing.T) {
+	a1 := newTestAssets(t)
+	a2 := newTestAssets(t)
+
+	fp := a1.Path("/js/app.js")
+	if fp == "/js/app.js" {
+		t.Fatalf("Expected /js/app.js to be fingerprinted")
+	}
+	if !strings.HasPrefix(fp, "/js/app.") || !strings.HasSuffix(fp, ".js") || len(fp) != len("/js/app.12345678.js") {
+		t.Errorf("Unexpected fingerprinted path %q", fp)
+	}
+	if got := a2.Path("/js/app.js"); got != fp {
+		t.Errorf("Fingerprint not stable across loads: %q vs %q", fp, got)
+	}
+
+	changed := testFS()
+	changed["js/app.js"] = &fstest.MapFile{Data: []byte(`console.log("changed");`)}
+	a3, err := New(changed)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	if got := a3.Path("/js/app.js"); got == fp {
+		t.Errorf("Expected fingerprint to change with contents, still %q", got)
+	}
+}
+
+func TestNew_NotFingerprinted(t *testing.T) {
+	a := newTestAssets(t)
+	for _, path := range []string{"/index.html", "/about.html", "/sw.js", "/missing.js"} {
+		if got := a.Path(path); got != path {
+			t.Errorf("Path(%q) = %q, want unchanged", path, got)
+		}
+	}
+}
+
+func TestNew_RendersAssetReferences(t *testing.T) {
+	a := newTestAssets(t)
+	rec := get(a, "/", nil)
+	body := rec.Body.String()
+
+	for _, want := range []string{a.Path("/css/app.css"), a.Path("/js/app.js")} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Expected rendered index.html to reference %q, got %s", want, body)
+		}
+	}
+	if strings.Contains(body, "{{") {
+		t.Errorf("Template actions left in rendered page: %s", body)
+	}
+}
+
+func TestNew_UnknownAssetReference(t *testing.T) {
+	fsys := testFS()
+	fsys["index.html"] = &fstest.MapFile{Data: []byte(`<script src="{{asset "/js/typo.js"}}"></script>`)}
+	if _, err := New(fsys); err == nil {
+		t.Errorf("Expected an error for a reference to an unknown asset")
+	}
+}
+
+func TestNew_Generated(t *testing.T) {
+	a, err := New(testFS(),
+		File{Path: "/js/words.js", Content: []byte(`export const WORDS = [];`)},
+		File{Path: "/js/app.js", Content: []byte(`console.log("generated");`)},
+	)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	rec := get(a, "/js/words.js", nil)
+	if rec.Code != http.StatusOK || rec.Body.String() != `export const WORDS = [];` {
+		t.Errorf("Expected generated file to be served, got %d %q", rec.Code, rec.Body)
+	}
+	if _, ok := a.Manifest().Assets["/js/words.js"]; !ok {
+		t.Errorf("Expected generated file in the manifest")
+	}
+	if rec := get(a, "/js/app.js", nil); rec.Body.String() != `console.log("generated");` {
+		t.Errorf("Expected generated file to replace the embedded one, got %q", rec.Body)
+	}
+}
+
+func TestServeHTTP_Fingerprinted(t *testing.T) {
+	a := newTestAssets(t)
+	rec := get(a, a.Path("/js/app.js"), nil)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", rec.Code)
+	}
+	if got := rec.Header().Get("Cache-Control"); got != "public, max-age=31536000, immutable" {
+		t.Errorf("Unexpected Cache-Control %q", got)
+	}
+	if got := rec.Header().Get("Content-Type"); !strings.HasPrefix(got, "text/javascript") {
+		t.Errorf("Unexpected Content-Type %q", got)
+	}
+	if rec.Body.String() != `console.log("app");` {
+		t.Errorf("Unexpected body %q", rec.Body.String())
+	}
+}
+
+func TestServeHTTP_Revalidate(t *testing.T) {
+	a := newTestAssets(t)
+
+	for _, path := range []string{"/js/app.js", "/", "/sw.js"} {
+		rec := get(a, path, nil)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: expected 200, got %d", path, rec.Code)
+		}
+		if got := rec.Header().Get("Cache-Control"); got != "no-cache" {
+			t.Errorf("%s: unexpected Cache-Control %q", path, got)
+		}
+		etag := rec.Header().Get("ETag")
+		if etag == "" {
+			t.Fatalf("%s: expected an ETag", path)
+		}
+
+		rec = get(a, path, http.Header{"If-None-Match": {etag}})
+		if rec.Code != http.StatusNotModified {
+			t.Errorf("%s: expected 304 for matching ETag, got %d", path, rec.Code)
+		}
+		if rec.Body.Len() != 0 {
+			t.Errorf("%s: expected empty body on 304", path)
+		}
+
+		rec = get(a, path, http.Header{"If-None-Match": {`"stale"`}})
+		if rec.Code != http.StatusOK {
+			t.Errorf("%s: expected 200 for stale ETag, got %d", path, rec.Code)
+		}
+	}
+}
+
+func TestServeHTTP_NotFound(t *testing.T) {
+	a := newTestAssets(t)
+	for _, path := range []string{
+		"/js/missing.js",
+		"/js/app.00000000.js", // wrong hash
+		"/index.12345678.html",
+		"/css/",
+	} {
+		if rec := get(a, path, nil); rec.Code != http.StatusNotFound {
+			t.Errorf("%s: expected 404, got %d", path, rec.Code)
+		}
+	}
+}
+
+func TestHandleManifest(t *testing.T) {
+	a := newTestAssets(t)
+	rec := httptest.NewRecorder()
+	a.HandleManifest(rec, httptest.NewRequest(http.MethodGet, "/asset-manifest.json", nil))
+
+	if got := rec.Header().Get("Cache-Control"); got != "no-cache" {
+		t.Errorf("Unexpected Cache-Control %q", got)
+	}
+
+	var m Manifest
+	if err := json.Unmarshal(rec.Body.Bytes(), &m); err != nil {
+		t.Fatalf("Failed to decode manifest: %v", err)
+	}
+	if got := m.Assets["/js/app.js"].URL; got != a.Path("/js/app.js") {
+		t.Errorf("Manifest URL for /js/app.js = %q, want %q", got, a.Path("/js/app.js"))
+	}
+	if got := m.Assets["/sw.js"].URL; got != "/sw.js" {
+		t.Errorf("Manifest URL for /sw.js = %q, want unchanged", got)
+	}
+
+	// Revalidating an unchanged manifest is cheap
+	etag := rec.Header().Get("ETag")
+	if etag != `"`+m.Version+`"` {
+		t.Errorf("Expected ETag of the version %q, got %q", m.Version, etag)
+	}
+	req := httptest.NewRequest(http.MethodGet, "/asset-manifest.json", nil)
+	req.Header.Set("If-None-Match", etag)
+	rec = httptest.NewRecorder()
+	a.HandleManifest(rec, req)
+	if rec.Code != http.StatusNotModified {
+		t.Errorf("Expected 304, got %d", rec.Code)
+	}
+}
+
+func TestManifest_MatchesFS(t *testing.T) {
+	fsys := testFS()
+	a := newTestAssets(t)
+	m := a.Manifest()
+
+	if len(m.Assets) != len(fsys) {
+		t.Errorf("Expected %d manifest entries, got %d", len(fsys), len(m.Assets))
+	}
+	for name, file := range fsys {
+		entry, ok := m.Assets["/"+name]
+		if !ok {
+			t.Errorf("Missing manifest entry for %s", name)
+			continue
+		}
+
+		// Pages with asset references are listed as served, after rendering
+		content := file.Data
+		if name == "index.html" {
+			content = get(a, "/index.html", nil).Body.Bytes()
+		}
+		sum := sha256.Sum256(content)
+		if entry.Hash != hex.EncodeToString(sum[:]) || entry.Size != int64(len(content)) {
+			t.Errorf("Entry for %s = %+v, want hash %x and size %d", name, entry, sum, len(content))
+		}
+
+		// The hash must agree with what revalidation uses
+		rec := get(a, entry.URL, nil)
+		if etag := rec.Header().Get("ETag"); etag != `"`+entry.Hash[:16]+`"` {
+			t.Errorf("ETag for %s = %s, want a prefix of %s", entry.URL, etag, entry.Hash)
+		}
+	}
+}
+
+func TestManifest_Version(t *testing.T) {
+	v1 := newTestAssets(t).Manifest().Version
+	if v1 == "" || v1 != newTestAssets(t).Manifest().Version {
+		t.Fatalf("Expected a stable version, got %q", v1)
+	}
+
+	for name, change := range map[string]func(fstest.MapFS){
+		"changed": func(fsys fstest.MapFS) { fsys["about.html"] = &fstest.MapFile{Data: []byte(`<p>New</p>`)} },
+		"added":   func(fsys fstest.MapFS) { fsys["js/new.js"] = &fstest.MapFile{Data: []byte(`1`)} },
+		"removed": func(fsys fstest.MapFS) { delete(fsys, "about.html") },
+	} {
+		fsys := testFS()
+		change(fsys)
+		a, err := New(fsys)
+		if err != nil {
+			t.Fatalf("New failed: %v", err)
+		}
+		if v := a.Manifest().Version; v == v1 {
+			t.Errorf("Expected version to change when an asset is %s", name)
+		}
+	}
+}
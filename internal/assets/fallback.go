@@ -0,0 +1,98 @@
+package assets
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// notFoundPage is the asset served (with status 404) for unknown pages
+const notFoundPage = "/404.html"
+
+// FallbackOptions configures how Fallback classifies unknown paths
+type FallbackOptions struct {
+	// APIPrefixes get a JSON not-found body (e.g. "/api/", "/kv/")
+	APIPrefixes []string
+
+	// ReservedPrefixes are never answered with the app shell, even for
+	// browsers (e.g. "/auth/", "/css/", "/js/")
+	ReservedPrefixes []string
+}
+
+// Fallback returns the handler for the catch-all "/" route. Known assets
+// are served as usual. For unknown paths:
+//   - API paths get a JSON {"error": "not found"} 404
+//   - browser navigations (GET/HEAD accepting text/html) outside reserved
+//     prefixes get index.html, so client-side routes like /t/abc123 work
+//   - everything else gets the branded 404 page
+func (a *Assets) Fallback(opts FallbackOptions) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		urlPath := r.URL.Path
+		if strings.HasSuffix(urlPath, "/") {
+			urlPath += "index.html"
+		}
+		if _, ok := a.Lookup(urlPath); ok {
+			a.ServeHTTP(w, r)
+			return
+		}
+
+		switch {
+		case hasAnyPrefix(r.URL.Path, opts.APIPrefixes):
+			writeJSONNotFound(w)
+		case isNavigation(r) && !hasAnyPrefix(r.URL.Path, opts.ReservedPrefixes):
+			a.serveAppShell(w, r)
+		default:
+			a.NotFound(w, r)
+		}
+	})
+}
+
+// NotFound writes the branded 404 page, or a plain 404 if there isn't one
+func (a *Assets) NotFound(w http.ResponseWriter, r *http.Request) {
+	page, ok := a.byPath[notFoundPage]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", revalidateCacheControl)
+	w.WriteHeader(http.StatusNotFound)
+	if r.Method != http.MethodHead {
+		w.Write(page.content)
+	}
+}
+
+// serveAppShell serves index.html for a client-side route
+func (a *Assets) serveAppShell(w http.ResponseWriter, r *http.Request) {
+	index, ok := a.byPath["/index.html"]
+	if !ok {
+		a.NotFound(w, r)
+		return
+	}
+	w.Header().Add("Vary", "Accept")
+	serve(w, r, index, revalidateCacheControl)
+}
+
+// isNavigation reports whether r looks like a browser loading a page
+func isNavigation(r *http.Request) bool {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+	return strings.Contains(r.Header.Get("Accept"), "text/html")
+}
+
+func hasAnyPrefix(s string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(s, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func writeJSONNotFound(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusNotFound)
+	json.NewEncoder(w).Encode(map[string]string{"error": "not found"})
+}
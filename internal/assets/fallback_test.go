@@ -0,0 +1,91 @@
+package assets
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func newFallbackHandler(t *testing.T) (*Assets, http.Handler) {
+	t.Helper()
+	fsys := testFS()
+	fsys["404.html"] = &fstest.MapFile{Data: []byte(`<h1>Trifling</h1><p>Not found</p>`)}
+	a, err := New(fsys)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	return a, a.Fallback(FallbackOptions{
+		APIPrefixes:      []string{"/api/", "/kv/"},
+		ReservedPrefixes: []string{"/auth/", "/css/", "/js/"},
+	})
+}
+
+func TestFallback_Routes(t *testing.T) {
+	a, handler := newFallbackHandler(t)
+	const html = "text/html,application/xhtml+xml,*/*;q=0.8"
+
+	tests := []struct {
+		name        string
+		method      string
+		path        string
+		accept      string
+		wantStatus  int
+		wantType    string
+		wantBody    string
+		wantNoCache bool
+	}{
+		{"root", "GET", "/", html, 200, "text/html", "/js/app.", true},
+		{"real page", "GET", "/about.html", html, 200, "text/html", "About", true},
+		{"real asset", "GET", "/js/app.js", "*/*", 200, "text/javascript", "console.log", true},
+		{"client route", "GET", "/t/abc123", html, 200, "text/html", "/js/app.", true},
+		{"client route HEAD", "HEAD", "/t/abc123", html, 200, "text/html", "", true},
+		{"client route non-browser", "GET", "/t/abc123", "*/*", 404, "text/html", "Not found", true},
+		{"client route POST", "POST", "/t/abc123", html, 404, "text/html", "Not found", true},
+		{"unknown api", "GET", "/api/nope", html, 404, "application/json", `{"error":"not found"}`, false},
+		{"unknown kv", "GET", "/kv/nope", "application/json", 404, "application/json", `{"error":"not found"}`, false},
+		{"unknown auth", "GET", "/auth/nope", html, 404, "text/html", "Not found", true},
+		{"missing js", "GET", "/js/missing.js", html, 404, "text/html", "Not found", true},
+		{"missing css", "GET", "/css/missing.css", "text/css", 404, "text/html", "Not found", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			req.Header.Set("Accept", tt.accept)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("Expected status %d, got %d", tt.wantStatus, rec.Code)
+			}
+			if got := rec.Header().Get("Content-Type"); !strings.HasPrefix(got, tt.wantType) {
+				t.Errorf("Expected Content-Type %q, got %q", tt.wantType, got)
+			}
+			if !strings.Contains(rec.Body.String(), tt.wantBody) {
+				t.Errorf("Expected body to contain %q, got %q", tt.wantBody, rec.Body.String())
+			}
+			if tt.wantNoCache && rec.Header().Get("Cache-Control") != "no-cache" {
+				t.Errorf("Expected Cache-Control no-cache, got %q", rec.Header().Get("Cache-Control"))
+			}
+		})
+	}
+
+	// Fingerprinted assets still resolve through the catch-all
+	req := httptest.NewRequest(http.MethodGet, a.Path("/css/app.css"), nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || rec.Header().Get("Cache-Control") != immutableCacheControl {
+		t.Errorf("Expected fingerprinted asset to be served immutable, got %d %q", rec.Code, rec.Header().Get("Cache-Control"))
+	}
+}
+
+func TestFallback_NoNotFoundPage(t *testing.T) {
+	a := newTestAssets(t)
+	rec := httptest.NewRecorder()
+	a.NotFound(rec, httptest.NewRequest(http.MethodGet, "/nope", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404, got %d", rec.Code)
+	}
+}
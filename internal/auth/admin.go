@@ -0,0 +1,68 @@
+package auth
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+)
+
+// AdminGate decides whether a request comes from an administrator, either
+// through a logged-in session whose email is on the admin list or through
+// a bearer token
+type AdminGate struct {
+	sessionMgr *SessionManager
+	emails     map[string]bool
+	token      string
+}
+
+// NewAdminGate creates an admin gate. An empty token disables bearer token access.
+func NewAdminGate(sessionMgr *SessionManager, emails []string, token string) *AdminGate {
+	normalized := make(map[string]bool, len(emails))
+	for _, email := range emails {
+		normalized[strings.ToLower(strings.TrimSpace(email))] = true
+	}
+	return &AdminGate{
+		sessionMgr: sessionMgr,
+		emails:     normalized,
+		token:      token,
+	}
+}
+
+// check returns the HTTP status to reject the request with, or 0 if the
+// caller is an admin
+func (g *AdminGate) check(r *http.Request) int {
+	if bearer, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		if g.token != "" && subtle.ConstantTimeCompare([]byte(bearer), []byte(g.token)) == 1 {
+			return 0
+		}
+		return http.StatusForbidden
+	}
+
+	session, err := g.sessionMgr.GetSession(r)
+	if err != nil || !session.Authenticated {
+		return http.StatusUnauthorized
+	}
+	if !g.emails[strings.ToLower(session.Email)] {
+		return http.StatusForbidden
+	}
+	return 0
+}
+
+// IsAdmin reports whether the request comes from an administrator
+func (g *AdminGate) IsAdmin(r *http.Request) bool {
+	return g.check(r) == 0
+}
+
+// Require wraps a handler so only administrators can reach it
+func (g *AdminGate) Require(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch g.check(r) {
+		case 0:
+			next.ServeHTTP(w, r)
+		case http.StatusUnauthorized:
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		default:
+			http.Error(w, "Forbidden", http.StatusForbidden)
+		}
+	})
+}
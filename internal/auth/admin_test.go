@@ -0,0 +1,76 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestAdminGate(t *testing.T) {
+	sessionMgr := NewSessionManager(false, time.Hour)
+
+	// login creates an authenticated session and returns its cookie
+	login := func(email string) *http.Cookie {
+		rec := httptest.NewRecorder()
+		session, err := sessionMgr.GetOrCreateSession(httptest.NewRequest(http.MethodGet, "/", nil), rec)
+		if err != nil {
+			t.Fatalf("Failed to create session: %v", err)
+		}
+		session.Email = email
+		session.Authenticated = true
+		return rec.Result().Cookies()[0]
+	}
+
+	adminCookie := login("Admin@Example.com")
+	userCookie := login("user@example.com")
+
+	gate := NewAdminGate(sessionMgr, []string{"admin@example.com"}, "s3cret")
+	handler := gate.Require(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name       string
+		cookie     *http.Cookie
+		auth       string
+		wantStatus int
+	}{
+		{name: "anonymous", wantStatus: http.StatusUnauthorized},
+		{name: "non-admin session", cookie: userCookie, wantStatus: http.StatusForbidden},
+		{name: "admin session (case-insensitive)", cookie: adminCookie, wantStatus: http.StatusOK},
+		{name: "valid bearer token", auth: "Bearer s3cret", wantStatus: http.StatusOK},
+		{name: "wrong bearer token", auth: "Bearer nope", wantStatus: http.StatusForbidden},
+		{name: "wrong token beats admin cookie", cookie: adminCookie, auth: "Bearer nope", wantStatus: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/admin", nil)
+			if tt.cookie != nil {
+				req.AddCookie(tt.cookie)
+			}
+			if tt.auth != "" {
+				req.Header.Set("Authorization", tt.auth)
+			}
+
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("Expected %d, got %d", tt.wantStatus, rec.Code)
+			}
+		})
+	}
+}
+
+func TestAdminGate_EmptyTokenDisablesBearer(t *testing.T) {
+	gate := NewAdminGate(NewSessionManager(false, time.Hour), nil, "")
+
+	req := httptest.NewRequest(http.MethodGet, "/admin", nil)
+	req.Header.Set("Authorization", "Bearer ")
+
+	if gate.IsAdmin(req) {
+		t.Errorf("Expected empty bearer token to be rejected when no token is configured")
+	}
+}
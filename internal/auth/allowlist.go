@@ -99,9 +99,14 @@ func loadAllowlist(filePath string) ([]string, error) {
 
 // IsAllowed checks if an email is allowed by the allowlist
 func (a *Allowlist) IsAllowed(email string) bool {
+	return Allows(a.patterns, email)
+}
+
+// Allows reports whether any of the patterns (emails or @domains) allows email
+func Allows(patterns []string, email string) bool {
 	email = strings.ToLower(strings.TrimSpace(email))
 
-	for _, pattern := range a.patterns {
+	for _, pattern := range patterns {
 		pattern = strings.ToLower(strings.TrimSpace(pattern))
 
 		// Check for domain wildcard (e.g., "@anthropic.com")
@@ -120,3 +125,113 @@ func (a *Allowlist) IsAllowed(email string) bool {
 
 	return false
 }
+
+// ValidatePattern checks that an allowlist pattern is an email address
+// ("alice@example.com") or a domain wildcard ("@example.com")
+func ValidatePattern(pattern string) error {
+	at := strings.LastIndex(pattern, "@")
+	if at == -1 || at == len(pattern)-1 || strings.ContainsAny(pattern, " \t#") || strings.Count(pattern, "@") > 1 {
+		return fmt.Errorf("invalid allowlist pattern %q: want user@domain or @domain", pattern)
+	}
+	return nil
+}
+
+// ReadAllowlist returns the patterns in an allowlist file
+func ReadAllowlist(filePath string) ([]string, error) {
+	patterns, err := loadAllowlist(filePath)
+	if os.IsNotExist(err) {
+		return defaultAllowlist, nil
+	}
+	return patterns, err
+}
+
+// AddToAllowlist appends a pattern to an allowlist file, creating the file
+// with the default patterns first if needed. It reports false if the
+// pattern was already present.
+func AddToAllowlist(filePath, pattern string) (bool, error) {
+	pattern = strings.ToLower(strings.TrimSpace(pattern))
+	if err := ValidatePattern(pattern); err != nil {
+		return false, err
+	}
+
+	if _, err := os.Stat(filePath); os.IsNotExist(err) {
+		if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+			return false, fmt.Errorf("failed to create directory: %w", err)
+		}
+		if err := createDefaultAllowlist(filePath); err != nil {
+			return false, fmt.Errorf("failed to create default allowlist: %w", err)
+		}
+	}
+
+	patterns, err := loadAllowlist(filePath)
+	if err != nil {
+		return false, fmt.Errorf("failed to load allowlist: %w", err)
+	}
+	for _, p := range patterns {
+		if strings.EqualFold(p, pattern) {
+			return false, nil
+		}
+	}
+
+	file, err := os.OpenFile(filePath, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+
+	if _, err := file.WriteString(pattern + "\n"); err != nil {
+		return false, err
+	}
+	return true, file.Close()
+}
+
+// RemoveFromAllowlist removes a pattern from an allowlist file, keeping
+// comments and other lines intact. It reports false if the pattern wasn't
+// present.
+func RemoveFromAllowlist(filePath, pattern string) (bool, error) {
+	pattern = strings.TrimSpace(pattern)
+
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	var kept []string
+	removed := false
+	for _, line := range strings.SplitAfter(string(data), "\n") {
+		if strings.EqualFold(strings.TrimSpace(line), pattern) {
+			removed = true
+			continue
+		}
+		kept = append(kept, line)
+	}
+	if !removed {
+		return false, nil
+	}
+
+	// Write a temp file and rename it so a crash can't truncate the list
+	tmp, err := os.CreateTemp(filepath.Dir(filePath), ".allowlist-*")
+	if err != nil {
+		return false, err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := tmp.Chmod(0644); err != nil {
+		tmp.Close()
+		return false, err
+	}
+	if _, err := tmp.WriteString(strings.Join(kept, "")); err != nil {
+		tmp.Close()
+		return false, err
+	}
+	if err := tmp.Close(); err != nil {
+		return false, err
+	}
+	if err := os.Rename(tmp.Name(), filePath); err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -8,7 +8,6 @@ import (
 	"log/slog"
 	"net/http"
 	"net/url"
-	"os"
 
 	"golang.org/x/oauth2"
 	"golang.org/x/oauth2/google"
@@ -66,7 +65,7 @@ func (oc *OAuthConfig) HandleLogin(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	session.OAuthState = state
-	if err := oc.SessionMgr.Save(w, session); err != nil {
+	if err := oc.SessionMgr.Save(w, r, session); err != nil {
 		http.Error(w, "Failed to save session", http.StatusInternalServerError)
 		return
 	}
@@ -156,7 +155,7 @@ func (oc *OAuthConfig) HandleCallback(w http.ResponseWriter, r *http.Request) {
 	session.Authenticated = true
 	session.OAuthState = "" // Clear the state token
 
-	if err := oc.SessionMgr.Save(w, session); err != nil {
+	if err := oc.SessionMgr.Save(w, r, session); err != nil {
 		slog.Error("Failed to save session", "error", err)
 		redirectWithError("Failed to save login session. Please try again.")
 		return
@@ -196,18 +195,3 @@ func (oc *OAuthConfig) HandleLogout(w http.ResponseWriter, r *http.Request) {
 	// Redirect to home page
 	http.Redirect(w, r, "/", http.StatusSeeOther)
 }
-
-// GetOAuthCredentials retrieves OAuth credentials from environment
-func GetOAuthCredentials() (clientID, clientSecret string, err error) {
-	clientID = os.Getenv("GOOGLE_CLIENT_ID")
-	clientSecret = os.Getenv("GOOGLE_CLIENT_SECRET")
-
-	if clientID == "" {
-		return "", "", fmt.Errorf("GOOGLE_CLIENT_ID not set")
-	}
-	if clientSecret == "" {
-		return "", "", fmt.Errorf("GOOGLE_CLIENT_SECRET not set")
-	}
-
-	return clientID, clientSecret, nil
-}
@@ -3,22 +3,22 @@ package auth
 import (
 	"fmt"
 	"net/http"
+	"strings"
 	"sync"
 	"time"
-)
 
-const (
-	sessionCookieName = "trifle_session"
-	sessionDuration   = 24 * time.Hour * 7 // 7 days
+	"github.com/zellyn/trifle/internal/middleware"
 )
 
+const sessionCookieName = "trifle_session"
+
 // Session represents a user session (in-memory only for Phase 2)
 type Session struct {
 	ID            string
 	UserID        string // User ID from storage
 	Email         string
 	Authenticated bool
-	OAuthState    string    // Temporary state for OAuth flow
+	OAuthState    string // Temporary state for OAuth flow
 	CreatedAt     time.Time
 	LastAccessed  time.Time
 }
@@ -37,19 +37,37 @@ func (s *Session) IsAuthenticated() bool {
 type SessionManager struct {
 	sessions map[string]*Session
 	mu       sync.RWMutex
-	secure   bool  // Use secure cookies (set to true in production)
+	secure   bool          // Always use secure cookies (set to true in production)
+	lifetime time.Duration // Session cookie lifetime
 }
 
 // NewSessionManager creates a new session manager
-func NewSessionManager(secure bool) *SessionManager {
+func NewSessionManager(secure bool, lifetime time.Duration) *SessionManager {
 	return &SessionManager{
 		sessions: make(map[string]*Session),
 		secure:   secure,
+		lifetime: lifetime,
 	}
 }
 
 // GetSession retrieves a session from a request
 func (sm *SessionManager) GetSession(r *http.Request) (*Session, error) {
+	session, err := sm.PeekSession(r)
+	if err != nil {
+		return nil, err
+	}
+
+	// Update last accessed time
+	sm.mu.Lock()
+	session.LastAccessed = time.Now()
+	sm.mu.Unlock()
+
+	return session, nil
+}
+
+// PeekSession is GetSession without marking the session as used, for
+// lookups such as access logging that shouldn't count as activity
+func (sm *SessionManager) PeekSession(r *http.Request) (*Session, error) {
 	cookie, err := r.Cookie(sessionCookieName)
 	if err != nil {
 		return nil, err
@@ -62,13 +80,52 @@ func (sm *SessionManager) GetSession(r *http.Request) (*Session, error) {
 	if !exists {
 		return nil, fmt.Errorf("session not found")
 	}
+	return session, nil
+}
+
+// Count returns the number of sessions currently held in memory
+func (sm *SessionManager) Count() int {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+	return len(sm.sessions)
+}
+
+// LastSeen returns, for each logged-in email, when one of its sessions was
+// last used. Sessions are in memory, so this only covers time since the
+// server started.
+func (sm *SessionManager) LastSeen() map[string]time.Time {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+
+	seen := make(map[string]time.Time)
+	for _, session := range sm.sessions {
+		if !session.Authenticated {
+			continue
+		}
+		email := strings.ToLower(session.Email)
+		if session.LastAccessed.After(seen[email]) {
+			seen[email] = session.LastAccessed
+		}
+	}
+	return seen
+}
 
-	// Update last accessed time
-	sm.mu.Lock()
-	session.LastAccessed = time.Now()
-	sm.mu.Unlock()
+// RemoveExpired drops sessions not accessed within the session lifetime
+// (their cookies have already expired) and returns how many were removed
+func (sm *SessionManager) RemoveExpired() int {
+	cutoff := time.Now().Add(-sm.lifetime)
 
-	return session, nil
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	removed := 0
+	for id, session := range sm.sessions {
+		if session.LastAccessed.Before(cutoff) {
+			delete(sm.sessions, id)
+			removed++
+		}
+	}
+	return removed
 }
 
 // GetOrCreateSession gets an existing session or creates a new one
@@ -99,19 +156,19 @@ func (sm *SessionManager) GetOrCreateSession(r *http.Request, w http.ResponseWri
 	sm.mu.Unlock()
 
 	// Set cookie
-	sm.setCookie(w, sessionID)
+	sm.setCookie(w, r, sessionID)
 
 	return session, nil
 }
 
 // Save saves a session (updates it in memory and refreshes the cookie)
-func (sm *SessionManager) Save(w http.ResponseWriter, session *Session) error {
+func (sm *SessionManager) Save(w http.ResponseWriter, r *http.Request, session *Session) error {
 	// Update in memory cache
 	sm.mu.Lock()
 	sm.sessions[session.ID] = session
 	sm.mu.Unlock()
 
-	sm.setCookie(w, session.ID)
+	sm.setCookie(w, r, session.ID)
 	return nil
 }
 
@@ -132,20 +189,27 @@ func (sm *SessionManager) Destroy(w http.ResponseWriter, r *http.Request) {
 		Path:     "/",
 		MaxAge:   -1,
 		HttpOnly: true,
-		Secure:   sm.secure,
+		Secure:   sm.cookieSecure(r),
 		SameSite: http.SameSiteLaxMode,
 	})
 }
 
+// cookieSecure reports whether the session cookie gets the Secure
+// attribute: always in production, and otherwise whenever the original
+// request was HTTPS, as seen through any trusted proxy
+func (sm *SessionManager) cookieSecure(r *http.Request) bool {
+	return sm.secure || middleware.Scheme(r) == "https"
+}
+
 // setCookie sets the session cookie
-func (sm *SessionManager) setCookie(w http.ResponseWriter, sessionID string) {
+func (sm *SessionManager) setCookie(w http.ResponseWriter, r *http.Request, sessionID string) {
 	http.SetCookie(w, &http.Cookie{
 		Name:     sessionCookieName,
 		Value:    sessionID,
 		Path:     "/",
-		MaxAge:   int(sessionDuration.Seconds()),
+		MaxAge:   int(sm.lifetime.Seconds()),
 		HttpOnly: true,
-		Secure:   sm.secure,
+		Secure:   sm.cookieSecure(r),
 		SameSite: http.SameSiteLaxMode, // Lax allows OAuth callback redirects
 	})
 }
@@ -0,0 +1,146 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/zellyn/trifle/internal/middleware"
+)
+
+func TestSessionManager_RemoveExpired(t *testing.T) {
+	sessionMgr := NewSessionManager(false, time.Hour)
+
+	newSession := func(lastAccessed time.Time) *Session {
+		session, err := sessionMgr.GetOrCreateSession(httptest.NewRequest(http.MethodGet, "/", nil), httptest.NewRecorder())
+		if err != nil {
+			t.Fatalf("Failed to create session: %v", err)
+		}
+		session.LastAccessed = lastAccessed
+		return session
+	}
+
+	newSession(time.Now().Add(-2 * time.Hour))
+	newSession(time.Now().Add(-61 * time.Minute))
+	fresh := newSession(time.Now().Add(-time.Minute))
+
+	if removed := sessionMgr.RemoveExpired(); removed != 2 {
+		t.Errorf("Expected 2 expired sessions removed, got %d", removed)
+	}
+	if got := sessionMgr.Count(); got != 1 {
+		t.Errorf("Expected 1 session left, got %d", got)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(&http.Cookie{Name: sessionCookieName, Value: fresh.ID})
+	if _, err := sessionMgr.GetSession(req); err != nil {
+		t.Errorf("Expected fresh session to survive: %v", err)
+	}
+}
+
+func TestSessionManager_LastSeen(t *testing.T) {
+	sessionMgr := NewSessionManager(false, time.Hour)
+
+	newSession := func(email string, authenticated bool, lastAccessed time.Time) {
+		session, err := sessionMgr.GetOrCreateSession(httptest.NewRequest(http.MethodGet, "/", nil), httptest.NewRecorder())
+		if err != nil {
+			t.Fatalf("Failed to create session: %v", err)
+		}
+		session.Email = email
+		session.Authenticated = authenticated
+		session.LastAccessed = lastAccessed
+	}
+
+	now := time.Now()
+	newSession("alice@example.com", true, now.Add(-time.Hour))
+	newSession("Alice@Example.com", true, now.Add(-time.Minute))
+	newSession("bob@example.com", false, now)
+
+	seen := sessionMgr.LastSeen()
+	if len(seen) != 1 {
+		t.Fatalf("Expected only alice to be seen, got %v", seen)
+	}
+	if !seen["alice@example.com"].Equal(now.Add(-time.Minute)) {
+		t.Errorf("Expected alice's most recent session, got %v", seen["alice@example.com"])
+	}
+}
+
+func TestSessionManager_PeekSession(t *testing.T) {
+	sessionMgr := NewSessionManager(false, time.Hour)
+	session, err := sessionMgr.GetOrCreateSession(httptest.NewRequest(http.MethodGet, "/", nil), httptest.NewRecorder())
+	if err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+	lastAccessed := time.Now().Add(-time.Hour)
+	session.LastAccessed = lastAccessed
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(&http.Cookie{Name: sessionCookieName, Value: session.ID})
+
+	peeked, err := sessionMgr.PeekSession(req)
+	if err != nil {
+		t.Fatalf("PeekSession failed: %v", err)
+	}
+	if peeked != session {
+		t.Errorf("Expected PeekSession to find the session")
+	}
+	if !session.LastAccessed.Equal(lastAccessed) {
+		t.Errorf("Expected PeekSession to leave LastAccessed alone, got %v", session.LastAccessed)
+	}
+
+	if _, err := sessionMgr.GetSession(req); err != nil {
+		t.Fatalf("GetSession failed: %v", err)
+	}
+	if !session.LastAccessed.After(lastAccessed) {
+		t.Errorf("Expected GetSession to update LastAccessed")
+	}
+
+	if _, err := sessionMgr.PeekSession(httptest.NewRequest(http.MethodGet, "/", nil)); err == nil {
+		t.Errorf("Expected an error without a session cookie")
+	}
+}
+
+func TestSessionManager_CookieSecureFollowsScheme(t *testing.T) {
+	proxies, err := middleware.ParseTrustedProxies([]string{"192.0.2.1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		production bool
+		url        string
+		forwarded  string
+		want       bool
+	}{
+		{"plain http", false, "http://example.com/", "", false},
+		{"direct https", false, "https://example.com/", "", true},
+		{"https behind trusted proxy", false, "http://example.com/", "https", true},
+		{"production over http", true, "http://example.com/", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sessionMgr := NewSessionManager(tt.production, time.Hour)
+			req := httptest.NewRequest(http.MethodGet, tt.url, nil)
+			if tt.forwarded != "" {
+				req.Header.Set("X-Forwarded-Proto", tt.forwarded)
+			}
+
+			rec := httptest.NewRecorder()
+			middleware.TrustedProxies(proxies)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if _, err := sessionMgr.GetOrCreateSession(r, w); err != nil {
+					t.Fatalf("Failed to create session: %v", err)
+				}
+			})).ServeHTTP(rec, req)
+
+			cookies := rec.Result().Cookies()
+			if len(cookies) != 1 {
+				t.Fatalf("Expected one cookie, got %v", cookies)
+			}
+			if cookies[0].Secure != tt.want {
+				t.Errorf("Expected Secure %v, got %v", tt.want, cookies[0].Secure)
+			}
+		})
+	}
+}
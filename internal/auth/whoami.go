@@ -5,6 +5,11 @@ import (
 	"net/http"
 )
 
+// WhoAmIResponse identifies the logged-in user
+type WhoAmIResponse struct {
+	Email string `json:"email"`
+}
+
 // HandleWhoAmI returns the current user's email if authenticated
 func HandleWhoAmI(sessionMgr *SessionManager) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -15,8 +20,6 @@ func HandleWhoAmI(sessionMgr *SessionManager) http.HandlerFunc {
 		}
 
 		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(map[string]string{
-			"email": session.Email,
-		})
+		json.NewEncoder(w).Encode(WhoAmIResponse{Email: session.Email})
 	}
 }
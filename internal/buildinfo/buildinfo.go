@@ -0,0 +1,102 @@
+// Package buildinfo reports what version of trifle is running, from the
+// build information stamped into the binary by the Go toolchain.
+package buildinfo
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"runtime"
+	"runtime/debug"
+	"strings"
+)
+
+// BuildDate is set at link time, e.g.
+//
+//	go build -ldflags "-X github.com/zellyn/trifle/internal/buildinfo.BuildDate=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+var BuildDate string
+
+// Info describes the running binary. Fields the build didn't record are empty.
+type Info struct {
+	Version    string `json:"version"`               // module version, "(devel)" for local builds
+	Revision   string `json:"revision,omitempty"`    // VCS commit
+	CommitTime string `json:"commit_time,omitempty"` // VCS commit time, RFC 3339
+	Modified   bool   `json:"modified,omitempty"`    // built from a tree with uncommitted changes
+	GoVersion  string `json:"go_version"`
+	BuildDate  string `json:"build_date,omitempty"`
+}
+
+// Get returns the build information of the running binary
+func Get() Info {
+	info := Info{
+		Version:   "(devel)",
+		GoVersion: runtime.Version(),
+		BuildDate: BuildDate,
+	}
+
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		return info
+	}
+	if bi.Main.Version != "" {
+		info.Version = bi.Main.Version
+	}
+	if bi.GoVersion != "" {
+		info.GoVersion = bi.GoVersion
+	}
+	for _, s := range bi.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			info.Revision = s.Value
+		case "vcs.time":
+			info.CommitTime = s.Value
+		case "vcs.modified":
+			info.Modified = s.Value == "true"
+		}
+	}
+	return info
+}
+
+// String formats the info for humans, e.g. for "trifle --version"
+func (i Info) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "trifle %s", i.Version)
+	if i.Revision != "" {
+		rev := i.Revision
+		if len(rev) > 12 {
+			rev = rev[:12]
+		}
+		fmt.Fprintf(&b, " (%s", rev)
+		if i.Modified {
+			b.WriteString(", modified")
+		}
+		if i.CommitTime != "" {
+			fmt.Fprintf(&b, ", committed %s", i.CommitTime)
+		}
+		b.WriteString(")")
+	}
+	fmt.Fprintf(&b, "\nbuilt with %s", i.GoVersion)
+	if i.BuildDate != "" {
+		fmt.Fprintf(&b, " on %s", i.BuildDate)
+	}
+	return b.String()
+}
+
+// LogAttrs returns the info as slog key/value pairs
+func (i Info) LogAttrs() []any {
+	return []any{
+		"version", i.Version,
+		"revision", i.Revision,
+		"commitTime", i.CommitTime,
+		"modified", i.Modified,
+		"goVersion", i.GoVersion,
+		"buildDate", i.BuildDate,
+	}
+}
+
+// HandleVersion serves the build information as JSON
+func HandleVersion(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(Get())
+}
@@ -0,0 +1,66 @@
+package buildinfo
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHandleVersion(t *testing.T) {
+	rec := httptest.NewRecorder()
+	HandleVersion(rec, httptest.NewRequest(http.MethodGet, "/api/version", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Expected JSON, got %q", ct)
+	}
+
+	var info Info
+	if err := json.NewDecoder(rec.Body).Decode(&info); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if !strings.HasPrefix(info.GoVersion, "go") {
+		t.Errorf("Expected a Go version, got %q", info.GoVersion)
+	}
+	if info.Version == "" {
+		t.Error("Expected a version")
+	}
+}
+
+func TestInfo_String(t *testing.T) {
+	tests := []struct {
+		name string
+		info Info
+		want string
+	}{
+		{
+			name: "no vcs",
+			info: Info{Version: "(devel)", GoVersion: "go1.25.2"},
+			want: "trifle (devel)\nbuilt with go1.25.2",
+		},
+		{
+			name: "stamped",
+			info: Info{
+				Version:    "v1.2.0",
+				Revision:   "0123456789abcdef",
+				CommitTime: "2025-01-31T12:00:00Z",
+				Modified:   true,
+				GoVersion:  "go1.25.2",
+				BuildDate:  "2025-02-01T00:00:00Z",
+			},
+			want: "trifle v1.2.0 (0123456789ab, modified, committed 2025-01-31T12:00:00Z)\nbuilt with go1.25.2 on 2025-02-01T00:00:00Z",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.info.String(); got != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,182 @@
+// Package codec lets API handlers speak JSON or MessagePack through one
+// interface. JSON is the default; clients opt into MessagePack with
+// "Accept: application/msgpack" on responses and "Content-Type:
+// application/msgpack" on request bodies, which carries binary values as
+// raw bytes instead of base64.
+package codec
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Codec marshals API documents in one encoding
+type Codec interface {
+	ContentType() string
+	Marshal(v any) ([]byte, error)
+	Unmarshal(data []byte, v any) error
+}
+
+// JSON is the default encoding
+var JSON Codec = jsonCodec{}
+
+type jsonCodec struct{}
+
+func (jsonCodec) ContentType() string                { return "application/json" }
+func (jsonCodec) Marshal(v any) ([]byte, error)      { return json.Marshal(v) }
+func (jsonCodec) Unmarshal(data []byte, v any) error { return json.Unmarshal(data, v) }
+
+func (jsonCodec) unmarshalStrict(data []byte, v any) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return errors.New("unexpected data after JSON value")
+	}
+	return nil
+}
+
+// strictUnmarshaler is implemented by codecs that can reject fields v
+// doesn't have
+type strictUnmarshaler interface {
+	unmarshalStrict(data []byte, v any) error
+}
+
+// codecs are the supported encodings, JSON first
+var codecs = []Codec{JSON, MessagePack}
+
+// ErrUnsupported is returned by ReadRequest for a request body in an
+// encoding no codec handles
+var ErrUnsupported = errors.New("unsupported content type")
+
+// forMediaType returns the codec for a media type like "application/json",
+// or nil. "application/x-msgpack" is accepted as an alias.
+func forMediaType(mediaType string) Codec {
+	if mediaType == "application/x-msgpack" {
+		return MessagePack
+	}
+	for _, c := range codecs {
+		if c.ContentType() == mediaType {
+			return c
+		}
+	}
+	return nil
+}
+
+// ForResponse picks the response codec from the request's Accept header:
+// the supported type with the highest quality value, the first listed on a
+// tie. Types with q=0 are refused. Anything else, including no header, gets
+// JSON.
+func ForResponse(r *http.Request) Codec {
+	best, bestQ := JSON, 0.0
+	for _, accept := range r.Header.Values("Accept") {
+		for part := range strings.SplitSeq(accept, ",") {
+			mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+			if err != nil {
+				continue
+			}
+			c := forMediaType(mediaType)
+			if c == nil {
+				continue
+			}
+			q := 1.0
+			if v, ok := params["q"]; ok {
+				if q, err = strconv.ParseFloat(v, 64); err != nil {
+					continue
+				}
+			}
+			if q > bestQ {
+				best, bestQ = c, q
+			}
+		}
+	}
+	return best
+}
+
+// ReadRequest decodes a request body of at most maxBytes into v, in the
+// encoding named by its Content-Type (JSON if there is none). Anything
+// after the first value is an error. Unknown fields are ignored, so older
+// servers accept requests from newer clients.
+func ReadRequest(w http.ResponseWriter, r *http.Request, maxBytes int64, v any) error {
+	return readRequest(w, r, maxBytes, v, false)
+}
+
+// ReadRequestStrict is ReadRequest, but fields v doesn't have are an error,
+// for small bodies where a misspelled field should fail loudly
+func ReadRequestStrict(w http.ResponseWriter, r *http.Request, maxBytes int64, v any) error {
+	return readRequest(w, r, maxBytes, v, true)
+}
+
+func readRequest(w http.ResponseWriter, r *http.Request, maxBytes int64, v any, strict bool) error {
+	c := JSON
+	if contentType := r.Header.Get("Content-Type"); contentType != "" {
+		mediaType, _, err := mime.ParseMediaType(contentType)
+		if err != nil {
+			return fmt.Errorf("%w: %q", ErrUnsupported, contentType)
+		}
+		if c = forMediaType(mediaType); c == nil {
+			return fmt.Errorf("%w: %q", ErrUnsupported, mediaType)
+		}
+	}
+
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBytes))
+	if err != nil {
+		return err
+	}
+	if strict {
+		return c.(strictUnmarshaler).unmarshalStrict(data, v)
+	}
+	return c.Unmarshal(data, v)
+}
+
+// WriteResponse encodes v in the encoding the request asked for and writes
+// it with the matching Content-Type. Responses vary by Accept, so caches
+// are told so.
+func WriteResponse(w http.ResponseWriter, r *http.Request, status int, v any) error {
+	c := ForResponse(r)
+	data, err := c.Marshal(v)
+	if err != nil {
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+		return err
+	}
+	w.Header().Set("Content-Type", c.ContentType())
+	w.Header().Add("Vary", "Accept")
+	w.WriteHeader(status)
+	_, err = w.Write(data)
+	return err
+}
+
+// Status returns the HTTP status for a ReadRequest error: 415 for an
+// unsupported encoding, 413 for an oversized body, and 400 otherwise
+func Status(err error) int {
+	var tooLarge *http.MaxBytesError
+	switch {
+	case errors.Is(err, ErrUnsupported):
+		return http.StatusUnsupportedMediaType
+	case errors.As(err, &tooLarge):
+		return http.StatusRequestEntityTooLarge
+	}
+	return http.StatusBadRequest
+}
+
+// RequestError writes the response for a ReadRequest error. Malformed
+// bodies get the invalid message, which should say what was expected.
+func RequestError(w http.ResponseWriter, err error, invalid string) {
+	switch status := Status(err); status {
+	case http.StatusUnsupportedMediaType:
+		http.Error(w, err.Error(), status)
+	case http.StatusRequestEntityTooLarge:
+		http.Error(w, "Request body too large", status)
+	default:
+		http.Error(w, invalid, status)
+	}
+}
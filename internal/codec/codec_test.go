@@ -0,0 +1,205 @@
+package codec
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestForResponse(t *testing.T) {
+	tests := []struct {
+		accept string
+		want   Codec
+	}{
+		{"", JSON},
+		{"*/*", JSON},
+		{"application/json", JSON},
+		{"application/msgpack", MessagePack},
+		{"application/x-msgpack", MessagePack},
+		{"text/html, application/msgpack;q=0.9", MessagePack},
+		{"application/json, application/msgpack", JSON},
+		{"application/msgpack;q=0", JSON},
+		{"application/json;q=0.5, application/msgpack", MessagePack},
+		{"application/msgpack;q=0.2, application/json;q=0.8", JSON},
+		{"application/msgpack;q=oops", JSON},
+		{"application/cbor", JSON},
+		{"not a media type", JSON},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodPost, "/", nil)
+		if tt.accept != "" {
+			r.Header.Set("Accept", tt.accept)
+		}
+		if got := ForResponse(r); got != tt.want {
+			t.Errorf("Accept %q: expected %s, got %s", tt.accept, tt.want.ContentType(), got.ContentType())
+		}
+	}
+}
+
+func TestReadRequest(t *testing.T) {
+	type doc struct {
+		Name string `json:"name"`
+	}
+	packed, err := MessagePack.Marshal(doc{Name: "msgpack"})
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		want        string
+		wantErr     error
+	}{
+		{"default json", "", `{"name":"json"}`, "json", nil},
+		{"json with charset", "application/json; charset=utf-8", `{"name":"json"}`, "json", nil},
+		{"msgpack", "application/msgpack", string(packed), "msgpack", nil},
+		{"unsupported", "application/cbor", "", "", ErrUnsupported},
+		{"too large", "", `{"name":"` + strings.Repeat("x", 100) + `"}`, "", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body))
+			if tt.contentType != "" {
+				r.Header.Set("Content-Type", tt.contentType)
+			}
+			var got doc
+			err := ReadRequest(httptest.NewRecorder(), r, 64, &got)
+			switch {
+			case tt.wantErr != nil:
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("Expected %v, got %v", tt.wantErr, err)
+				}
+			case tt.want == "":
+				if err == nil {
+					t.Error("Expected an error")
+				}
+			case err != nil:
+				t.Errorf("ReadRequest failed: %v", err)
+			case got.Name != tt.want:
+				t.Errorf("Expected %q, got %q", tt.want, got.Name)
+			}
+		})
+	}
+}
+
+func TestReadRequest_Status(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		wantStatus  int // 0 if the body should decode
+	}{
+		{"valid", "", `{"name":"ok"}`, 0},
+		{"unknown field ignored", "", `{"name":"ok","extra":1}`, 0},
+		{"malformed", "", `{"name":`, http.StatusBadRequest},
+		{"trailing data", "", `{"name":"ok"} {"name":"again"}`, http.StatusBadRequest},
+		{"msgpack trailing data", "application/msgpack", "\x80\x80", http.StatusBadRequest},
+		{"too large", "", `{"name":"` + strings.Repeat("x", 100) + `"}`, http.StatusRequestEntityTooLarge},
+		{"unsupported", "text/plain", "name", http.StatusUnsupportedMediaType},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body))
+			if tt.contentType != "" {
+				r.Header.Set("Content-Type", tt.contentType)
+			}
+			var got struct {
+				Name string `json:"name"`
+			}
+			err := ReadRequest(httptest.NewRecorder(), r, 64, &got)
+			if tt.wantStatus == 0 {
+				if err != nil {
+					t.Errorf("ReadRequest failed: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("Expected an error")
+			}
+			if status := Status(err); status != tt.wantStatus {
+				t.Errorf("Expected status %d, got %d (%v)", tt.wantStatus, status, err)
+			}
+
+			rec := httptest.NewRecorder()
+			RequestError(rec, err, "Expected a name")
+			if rec.Code != tt.wantStatus {
+				t.Errorf("RequestError wrote %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if tt.wantStatus == http.StatusBadRequest && !strings.Contains(rec.Body.String(), "Expected a name") {
+				t.Errorf("Expected the invalid message, got %q", rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestReadRequestStrict(t *testing.T) {
+	type doc struct {
+		Name string `json:"name"`
+	}
+	packed, _ := MessagePack.Marshal(map[string]any{"name": "x"})
+	packedExtra, _ := MessagePack.Marshal(map[string]any{"name": "x", "extra": 1})
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		wantErr     bool
+	}{
+		{"json", "", `{"name":"x"}`, false},
+		{"json unknown field", "", `{"name":"x","extra":1}`, true},
+		{"json trailing data", "", `{"name":"x"} {}`, true},
+		{"msgpack", "application/msgpack", string(packed), false},
+		{"msgpack unknown field", "application/msgpack", string(packedExtra), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body))
+			if tt.contentType != "" {
+				r.Header.Set("Content-Type", tt.contentType)
+			}
+			var got doc
+			err := ReadRequestStrict(httptest.NewRecorder(), r, 64, &got)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("Expected an error")
+				}
+				if status := Status(err); status != http.StatusBadRequest {
+					t.Errorf("Expected 400, got %d", status)
+				}
+				return
+			}
+			if err != nil || got.Name != "x" {
+				t.Errorf("ReadRequestStrict = %+v, %v", got, err)
+			}
+		})
+	}
+}
+
+func TestWriteResponse(t *testing.T) {
+	for _, c := range []Codec{JSON, MessagePack} {
+		r := httptest.NewRequest(http.MethodPost, "/", nil)
+		r.Header.Set("Accept", c.ContentType())
+		rec := httptest.NewRecorder()
+		if err := WriteResponse(rec, r, http.StatusCreated, map[string]int{"n": 1}); err != nil {
+			t.Fatalf("WriteResponse failed: %v", err)
+		}
+
+		if rec.Code != http.StatusCreated {
+			t.Errorf("Expected 201, got %d", rec.Code)
+		}
+		if got := rec.Header().Get("Content-Type"); got != c.ContentType() {
+			t.Errorf("Expected Content-Type %s, got %s", c.ContentType(), got)
+		}
+		if got := rec.Header().Get("Vary"); got != "Accept" {
+			t.Errorf("Expected Vary: Accept, got %q", got)
+		}
+		var got map[string]int
+		if err := c.Unmarshal(rec.Body.Bytes(), &got); err != nil || got["n"] != 1 {
+			t.Errorf("Expected {n: 1} back, got %v (%v)", got, err)
+		}
+	}
+}
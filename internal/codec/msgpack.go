@@ -0,0 +1,126 @@
+package codec
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/vmihailenco/msgpack/v5"
+	"github.com/vmihailenco/msgpack/v5/msgpcode"
+)
+
+// MessagePack encodes the same structures as JSON in MessagePack
+// (https://msgpack.org/), with []byte as raw binary rather than base64.
+//
+// Encoding uses the JSON field names, "-", and omitempty, and flattens
+// embedded structs, but doesn't apply ",string" (no response uses it).
+// Decoding goes through encoding/json, so requests follow exactly the same
+// rules in either encoding: case-insensitive field names, ",string", and (in
+// strict mode) unknown fields. Binary values reach []byte fields unchanged.
+var MessagePack Codec = msgpackCodec{}
+
+type msgpackCodec struct{}
+
+func (msgpackCodec) ContentType() string { return "application/msgpack" }
+
+func (msgpackCodec) Marshal(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := msgpack.NewEncoder(&buf)
+	enc.SetCustomStructTag("json")
+	enc.SetSortMapKeys(true)
+	enc.UseCompactInts(true)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (msgpackCodec) Unmarshal(data []byte, v any) error {
+	doc, err := toJSON(data)
+	if err != nil {
+		return err
+	}
+	return JSON.Unmarshal(doc, v)
+}
+
+func (msgpackCodec) unmarshalStrict(data []byte, v any) error {
+	doc, err := toJSON(data)
+	if err != nil {
+		return err
+	}
+	return jsonCodec{}.unmarshalStrict(doc, v)
+}
+
+// maxDepth bounds nesting, so a small hostile document can't exhaust the stack
+const maxDepth = 64
+
+// maxPrealloc caps the room reserved for an array or map from its header,
+// which a hostile document can set to billions
+const maxPrealloc = 1024
+
+// toJSON re-encodes one MessagePack document as JSON. Binary values become
+// base64 strings, which encoding/json decodes back into []byte.
+func toJSON(data []byte) ([]byte, error) {
+	r := bytes.NewReader(data)
+	v, err := decodeValue(msgpack.NewDecoder(r), 0)
+	if err != nil {
+		return nil, fmt.Errorf("msgpack: %w", err)
+	}
+	if r.Len() != 0 {
+		return nil, fmt.Errorf("msgpack: %d trailing bytes", r.Len())
+	}
+	doc, err := JSON.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("msgpack: %w", err)
+	}
+	return doc, nil
+}
+
+// decodeValue reads the next value as nil, a bool, a number, a string,
+// []byte, []any, or map[string]any
+func decodeValue(d *msgpack.Decoder, depth int) (any, error) {
+	if depth > maxDepth {
+		return nil, errors.New("nested too deeply")
+	}
+	c, err := d.PeekCode()
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case msgpcode.IsFixedArray(c) || c == msgpcode.Array16 || c == msgpcode.Array32:
+		n, err := d.DecodeArrayLen()
+		if err != nil || n == -1 {
+			return nil, err
+		}
+		list := make([]any, 0, min(n, maxPrealloc))
+		for range n {
+			v, err := decodeValue(d, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, v)
+		}
+		return list, nil
+	case msgpcode.IsFixedMap(c) || c == msgpcode.Map16 || c == msgpcode.Map32:
+		n, err := d.DecodeMapLen()
+		if err != nil || n == -1 {
+			return nil, err
+		}
+		m := make(map[string]any, min(n, maxPrealloc))
+		for range n {
+			key, err := d.DecodeString()
+			if err != nil {
+				return nil, fmt.Errorf("map key: %w", err)
+			}
+			if m[key], err = decodeValue(d, depth+1); err != nil {
+				return nil, err
+			}
+		}
+		return m, nil
+	case msgpcode.IsBin(c):
+		return d.DecodeBytes()
+	case msgpcode.IsExt(c):
+		return nil, errors.New("unsupported extension type")
+	}
+	return d.DecodeInterface()
+}
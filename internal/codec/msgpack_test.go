@@ -0,0 +1,181 @@
+package codec
+
+import (
+	"bytes"
+	"encoding/hex"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMessagePack_Encoding(t *testing.T) {
+	// Expected bytes from the MessagePack spec's format table
+	tests := []struct {
+		name string
+		v    any
+		want string
+	}{
+		{"nil", nil, "c0"},
+		{"false", false, "c2"},
+		{"true", true, "c3"},
+		{"positive fixint", 127, "7f"},
+		{"uint8", 128, "cc80"},
+		{"uint16", 256, "cd0100"},
+		{"uint32", 65536, "ce00010000"},
+		{"uint64", uint64(math.MaxUint64), "cfffffffffffffffff"},
+		{"negative fixint", -32, "e0"},
+		{"int8", -33, "d0df"},
+		{"int16", -129, "d1ff7f"},
+		{"int32", -32769, "d2ffff7fff"},
+		{"int64", int64(math.MinInt64), "d38000000000000000"},
+		{"float64", 1.5, "cb3ff8000000000000"},
+		{"fixstr", "hi", "a26869"},
+		{"str8", strings.Repeat("a", 32), "d920" + strings.Repeat("61", 32)},
+		{"bin8", []byte{1, 2}, "c4020102"},
+		{"fixarray", []string{"a"}, "91a161"},
+		{"fixmap", map[string]bool{"a": true}, "81a161c3"},
+		{"struct", struct {
+			A int    `json:"a"`
+			B string `json:"b,omitempty"`
+			C bool   `json:"-"`
+		}{A: 1}, "81a16101"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := MessagePack.Marshal(tt.v)
+			if err != nil {
+				t.Fatalf("Marshal failed: %v", err)
+			}
+			if hex.EncodeToString(got) != tt.want {
+				t.Errorf("Expected %s, got %x", tt.want, got)
+			}
+		})
+	}
+}
+
+type entry struct {
+	Key   string `json:"key"`
+	Size  int64  `json:"size"`
+	Value []byte `json:"value,omitempty"`
+}
+
+type document struct {
+	Entries []entry           `json:"entries"`
+	Extra   []string          `json:"extra"`
+	Labels  map[string]string `json:"labels,omitempty"`
+	Ratio   float64           `json:"ratio"`
+	Nested  *entry            `json:"nested,omitempty"`
+}
+
+func TestMessagePack_RoundTrip(t *testing.T) {
+	binary := make([]byte, 70000) // forces the 32-bit bin length
+	for i := range binary {
+		binary[i] = byte(i * 7)
+	}
+	want := document{
+		Entries: []entry{
+			{Key: "a", Size: int64(len(binary)), Value: binary},
+			{Key: strings.Repeat("k", 300), Size: -1},
+		},
+		Extra:  []string{},
+		Labels: map[string]string{"x": "y"},
+		Ratio:  0.25,
+		Nested: &entry{Key: "n", Value: []byte{0, 0xff}},
+	}
+
+	for _, c := range []Codec{JSON, MessagePack} {
+		data, err := c.Marshal(want)
+		if err != nil {
+			t.Fatalf("%s: Marshal failed: %v", c.ContentType(), err)
+		}
+		var got document
+		if err := c.Unmarshal(data, &got); err != nil {
+			t.Fatalf("%s: Unmarshal failed: %v", c.ContentType(), err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: round trip changed the document", c.ContentType())
+		}
+		if !bytes.Equal(got.Entries[0].Value, binary) {
+			t.Errorf("%s: binary value not byte-exact", c.ContentType())
+		}
+	}
+
+	jsonData, _ := JSON.Marshal(want)
+	packed, _ := MessagePack.Marshal(want)
+	t.Logf("JSON %d bytes, MessagePack %d bytes", len(jsonData), len(packed))
+	if len(packed) >= len(jsonData)*4/5 {
+		t.Errorf("Expected MessagePack to avoid base64's overhead: %d vs %d bytes", len(packed), len(jsonData))
+	}
+}
+
+func TestMessagePack_DecodeErrors(t *testing.T) {
+	deep := strings.Repeat("91", maxDepth+1) + "c0"
+	tests := []struct {
+		name string
+		data string
+		into any
+	}{
+		{"truncated string", "a3616263"[:6], new(string)},
+		{"trailing bytes", "c0c0", new(any)},
+		{"wrong type", "a161", new(int)},
+		{"overflow", "cd0100", new(int8)},
+		{"negative into uint", "ff", new(uint)},
+		{"non-string key", "810101", new(map[string]int)},
+		{"too deep", deep, new(any)},
+		{"huge array header", "dd7fffffff", new([]int)},
+		{"unsupported ext", "d40100", new(any)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := hex.DecodeString(tt.data)
+			if err != nil {
+				t.Fatalf("Bad test data: %v", err)
+			}
+			if err := MessagePack.Unmarshal(data, tt.into); err == nil {
+				t.Errorf("Expected an error decoding %s", tt.data)
+			}
+		})
+	}
+}
+
+func TestMessagePack_IgnoresUnknownFields(t *testing.T) {
+	data, _ := MessagePack.Marshal(map[string]any{"key": "a", "unknown": []int{1, 2}})
+	var got entry
+	if err := MessagePack.Unmarshal(data, &got); err != nil || got.Key != "a" {
+		t.Errorf("Expected key a, got %+v (%v)", got, err)
+	}
+}
+
+func TestMessagePack_FollowsJSONRules(t *testing.T) {
+	type base struct {
+		Key string `json:"key"`
+	}
+	type doc struct {
+		base
+		Size int64 `json:"size,string"`
+	}
+
+	// Embedded structs are flattened, as in JSON
+	packed, err := MessagePack.Marshal(doc{base: base{Key: "a"}, Size: 1})
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	var fields map[string]any
+	if err := MessagePack.Unmarshal(packed, &fields); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if _, ok := fields["key"]; !ok {
+		t.Errorf("Expected the embedded field at the top level, got %v", fields)
+	}
+
+	// Field names match case-insensitively, and ",string" is honored
+	packed, _ = MessagePack.Marshal(map[string]any{"KEY": "b", "size": "42"})
+	var got doc
+	if err := MessagePack.Unmarshal(packed, &got); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if got.Key != "b" || got.Size != 42 {
+		t.Errorf("Expected key b and size 42, got %+v", got)
+	}
+}
@@ -0,0 +1,480 @@
+// Package config loads server configuration from command-line flags,
+// environment variables, and an optional JSON config file.
+//
+// Precedence, from lowest to highest:
+//
+//  1. Built-in defaults
+//  2. Config file (path from --config or TRIFLE_CONFIG)
+//  3. Environment variables
+//  4. Command-line flags
+//
+// Config file keys use the same names as the flags, e.g.
+//
+//	{"port": "8080", "data-dir": "/var/lib/trifle", "write-timeout": "30s"}
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/netip"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config holds all server configuration
+type Config struct {
+	Port               string
+	DataDir            string
+	RedirectURL        string // OAuth redirect URL; defaults to http://localhost:{Port}/auth/callback
+	GoogleClientID     string
+	GoogleClientSecret string
+	SessionLifetime    time.Duration
+	ReadTimeout        time.Duration
+	ReadHeaderTimeout  time.Duration
+	WriteTimeout       time.Duration
+	IdleTimeout        time.Duration
+	UploadTimeout      time.Duration // Replaces Read/WriteTimeout for KV requests, which may carry large files
+	MaxHeaderBytes     int
+	MaxValueMB         int // Largest value a KV PUT may store
+	ShutdownTimeout    time.Duration
+	AdminEmails        []string // Emails allowed to use admin-only endpoints
+	AdminToken         string   // Bearer token for admin-only endpoints ("" disables)
+	CORSOrigins        []string // Origins allowed to make cross-origin requests (empty disables CORS)
+	CORSMethods        []string
+	CORSHeaders        []string
+	CORSCredentials    bool
+	CORSMaxAge         time.Duration
+	TrustedProxies     []string // Proxy CIDRs/IPs whose X-Forwarded-* headers are believed
+	AccessLog          string   // Access log file path ("" or "stdout" logs with the app logs)
+	AccessLogFormat    string   // "text" (slog) or "combined" (Combined Log Format)
+	AccessLogMaxSizeMB int      // Rotate the access log file at this size (0 disables)
+	AccessLogMaxAge    time.Duration
+	AccessLogKeep      int    // Rotated access log files to keep (0 keeps all)
+	Maintenance        bool   // Start in maintenance mode, refusing writes
+	LegacyKeys         bool   // Allow access to legacy user/{email}/ KV keys
+	WordListDir        string // Directory with adjectives.txt/nouns.txt replacing the display name words
+
+	// Production is inferred from the RedirectURL scheme (https = production)
+	Production bool
+
+	// PrintConfig asks main to dump the effective configuration and exit
+	PrintConfig bool
+}
+
+// Default returns the built-in defaults
+func Default() *Config {
+	return &Config{
+		Port:              "3000",
+		DataDir:           "./data",
+		SessionLifetime:   7 * 24 * time.Hour,
+		ReadTimeout:       15 * time.Second,
+		ReadHeaderTimeout: 5 * time.Second,
+		WriteTimeout:      15 * time.Second,
+		IdleTimeout:       60 * time.Second,
+		UploadTimeout:     5 * time.Minute,
+		MaxHeaderBytes:    64 << 10,
+		MaxValueMB:        32,
+		ShutdownTimeout:   15 * time.Second,
+		CORSMethods:       []string{"GET", "HEAD", "POST", "PUT", "DELETE"},
+		CORSHeaders:       []string{"Content-Type"},
+		CORSMaxAge:        10 * time.Minute,
+
+		AccessLogFormat:    "text",
+		AccessLogMaxSizeMB: 100,
+		AccessLogMaxAge:    24 * time.Hour,
+		AccessLogKeep:      7,
+		LegacyKeys:         true,
+	}
+}
+
+// field describes one configuration value and where it can come from
+type field struct {
+	name   string // flag name and config file key
+	env    string // environment variable ("" if none)
+	usage  string
+	secret bool
+	isBool bool // registered as a boolean flag, so "-name" alone means true
+	get    func(c *Config) string
+	set    func(c *Config, v string) error
+}
+
+func stringField(name, env, usage string, secret bool, ptr func(c *Config) *string) field {
+	return field{
+		name:   name,
+		env:    env,
+		usage:  usage,
+		secret: secret,
+		get:    func(c *Config) string { return *ptr(c) },
+		set: func(c *Config, v string) error {
+			*ptr(c) = v
+			return nil
+		},
+	}
+}
+
+func durationField(name, env, usage string, ptr func(c *Config) *time.Duration) field {
+	return field{
+		name:  name,
+		env:   env,
+		usage: usage,
+		get:   func(c *Config) string { return ptr(c).String() },
+		set: func(c *Config, v string) error {
+			d, err := time.ParseDuration(v)
+			if err != nil {
+				return err
+			}
+			*ptr(c) = d
+			return nil
+		},
+	}
+}
+
+func intField(name, env, usage string, ptr func(c *Config) *int) field {
+	return field{
+		name:  name,
+		env:   env,
+		usage: usage,
+		get:   func(c *Config) string { return strconv.Itoa(*ptr(c)) },
+		set: func(c *Config, v string) error {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				return err
+			}
+			*ptr(c) = n
+			return nil
+		},
+	}
+}
+
+func boolField(name, env, usage string, ptr func(c *Config) *bool) field {
+	return field{
+		name:   name,
+		env:    env,
+		usage:  usage,
+		isBool: true,
+		get:    func(c *Config) string { return strconv.FormatBool(*ptr(c)) },
+		set: func(c *Config, v string) error {
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return err
+			}
+			*ptr(c) = b
+			return nil
+		},
+	}
+}
+
+func listField(name, env, usage string, ptr func(c *Config) *[]string) field {
+	return field{
+		name:  name,
+		env:   env,
+		usage: usage,
+		get:   func(c *Config) string { return strings.Join(*ptr(c), ",") },
+		set: func(c *Config, v string) error {
+			var items []string
+			for _, item := range strings.Split(v, ",") {
+				if item = strings.TrimSpace(item); item != "" {
+					items = append(items, item)
+				}
+			}
+			*ptr(c) = items
+			return nil
+		},
+	}
+}
+
+// fields lists every configurable value, in the order --print-config shows them
+var fields = []field{
+	stringField("port", "PORT", "HTTP listen port", false,
+		func(c *Config) *string { return &c.Port }),
+	stringField("data-dir", "DATA_DIR", "directory for KV data and the allowlist", false,
+		func(c *Config) *string { return &c.DataDir }),
+	stringField("redirect-url", "OAUTH_REDIRECT_URL", "OAuth redirect URL (https enables production mode)", false,
+		func(c *Config) *string { return &c.RedirectURL }),
+	stringField("google-client-id", "GOOGLE_CLIENT_ID", "Google OAuth client ID", false,
+		func(c *Config) *string { return &c.GoogleClientID }),
+	stringField("google-client-secret", "GOOGLE_CLIENT_SECRET", "Google OAuth client secret", true,
+		func(c *Config) *string { return &c.GoogleClientSecret }),
+	durationField("session-lifetime", "SESSION_LIFETIME", "session cookie lifetime",
+		func(c *Config) *time.Duration { return &c.SessionLifetime }),
+	durationField("read-timeout", "READ_TIMEOUT", "HTTP server read timeout",
+		func(c *Config) *time.Duration { return &c.ReadTimeout }),
+	durationField("read-header-timeout", "READ_HEADER_TIMEOUT", "how long a client may take to send request headers",
+		func(c *Config) *time.Duration { return &c.ReadHeaderTimeout }),
+	durationField("write-timeout", "WRITE_TIMEOUT", "HTTP server write timeout",
+		func(c *Config) *time.Duration { return &c.WriteTimeout }),
+	durationField("idle-timeout", "IDLE_TIMEOUT", "HTTP server idle timeout",
+		func(c *Config) *time.Duration { return &c.IdleTimeout }),
+	durationField("upload-timeout", "UPLOAD_TIMEOUT", "read/write timeout for KV requests (large file uploads)",
+		func(c *Config) *time.Duration { return &c.UploadTimeout }),
+	intField("max-header-bytes", "MAX_HEADER_BYTES", "largest request header block accepted",
+		func(c *Config) *int { return &c.MaxHeaderBytes }),
+	intField("max-value-mb", "MAX_VALUE_MB", "largest value a KV PUT may store, in megabytes",
+		func(c *Config) *int { return &c.MaxValueMB }),
+	durationField("shutdown-timeout", "SHUTDOWN_TIMEOUT", "how long graceful shutdown may take",
+		func(c *Config) *time.Duration { return &c.ShutdownTimeout }),
+	listField("admin-emails", "ADMIN_EMAILS", "comma-separated emails allowed to use admin endpoints",
+		func(c *Config) *[]string { return &c.AdminEmails }),
+	stringField("admin-token", "ADMIN_TOKEN", "bearer token for admin endpoints (empty disables)", true,
+		func(c *Config) *string { return &c.AdminToken }),
+	listField("cors-origins", "CORS_ORIGINS", "comma-separated origins allowed to make cross-origin requests",
+		func(c *Config) *[]string { return &c.CORSOrigins }),
+	listField("cors-methods", "CORS_METHODS", "comma-separated methods allowed in CORS preflight",
+		func(c *Config) *[]string { return &c.CORSMethods }),
+	listField("cors-headers", "CORS_HEADERS", "comma-separated request headers allowed in CORS preflight",
+		func(c *Config) *[]string { return &c.CORSHeaders }),
+	boolField("cors-credentials", "CORS_CREDENTIALS", "allow cookies on cross-origin requests",
+		func(c *Config) *bool { return &c.CORSCredentials }),
+	durationField("cors-max-age", "CORS_MAX_AGE", "how long browsers may cache CORS preflight results",
+		func(c *Config) *time.Duration { return &c.CORSMaxAge }),
+	listField("trusted-proxies", "TRUSTED_PROXIES", "comma-separated proxy CIDRs or IPs trusted for X-Forwarded-For/-Proto",
+		func(c *Config) *[]string { return &c.TrustedProxies }),
+	stringField("access-log", "ACCESS_LOG", "access log file path (empty or \"stdout\" logs with the app logs)", false,
+		func(c *Config) *string { return &c.AccessLog }),
+	stringField("access-log-format", "ACCESS_LOG_FORMAT", "access log format: text or combined", false,
+		func(c *Config) *string { return &c.AccessLogFormat }),
+	intField("access-log-max-size-mb", "ACCESS_LOG_MAX_SIZE_MB", "rotate the access log file at this many megabytes (0 disables)",
+		func(c *Config) *int { return &c.AccessLogMaxSizeMB }),
+	durationField("access-log-max-age", "ACCESS_LOG_MAX_AGE", "rotate the access log file at this age (0 disables)",
+		func(c *Config) *time.Duration { return &c.AccessLogMaxAge }),
+	intField("access-log-keep", "ACCESS_LOG_KEEP", "number of rotated access log files to keep (0 keeps all)",
+		func(c *Config) *int { return &c.AccessLogKeep }),
+	boolField("maintenance", "MAINTENANCE", "start in maintenance mode (reads work, writes are refused)",
+		func(c *Config) *bool { return &c.Maintenance }),
+	boolField("legacy-keys", "LEGACY_KEYS", "allow access to legacy user/{email}/ keys (turn off after \"trifle keys migrate\")",
+		func(c *Config) *bool { return &c.LegacyKeys }),
+	stringField("wordlist-dir", "WORDLIST_DIR", "directory with adjectives.txt, nouns.txt, and/or excluded.txt replacing the built-in display name lists", false,
+		func(c *Config) *string { return &c.WordListDir }),
+}
+
+// Load builds a Config from defaults, the optional config file, the
+// environment (via getenv), and command-line args, then validates it.
+// With -print-config it skips validation, so the configuration can be
+// printed to debug the very problems validation would reject; the caller
+// should print it and then call Validate.
+func Load(args []string, getenv func(string) string) (*Config, error) {
+	cfg := Default()
+	fs := flag.NewFlagSet("trifle", flag.ContinueOnError)
+	fs.BoolVar(&cfg.PrintConfig, "print-config", false, "print the effective configuration and exit")
+	if err := cfg.load(fs, args, getenv, fields); err != nil {
+		return nil, err
+	}
+	if fs.NArg() > 0 {
+		return nil, fmt.Errorf("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+
+	if cfg.PrintConfig {
+		return cfg, nil
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// LoadCommand loads configuration for an administrative subcommand that
+// works on the data directory without starting the server. fs may define
+// command-specific flags; only -config and -data-dir are added to it, and
+// positional arguments are left in fs.Args(). Environment variables and the
+// config file apply as usual, but only storage settings are validated, so
+// OAuth credentials aren't required.
+func LoadCommand(fs *flag.FlagSet, args []string, getenv func(string) string) (*Config, error) {
+	var storageFields []field
+	for _, f := range fields {
+		if f.name == "data-dir" {
+			storageFields = append(storageFields, f)
+		}
+	}
+
+	cfg := Default()
+	if err := cfg.load(fs, args, getenv, storageFields); err != nil {
+		return nil, err
+	}
+	if cfg.DataDir == "" {
+		return nil, errors.New("data-dir must not be empty")
+	}
+	return cfg, nil
+}
+
+// load applies the config file, the environment, and flags (in increasing
+// precedence) on top of c. Only flagFields are registered as flags on fs.
+func (c *Config) load(fs *flag.FlagSet, args []string, getenv func(string) string, flagFields []field) error {
+	// Parse flags first so we know the config file path, but only apply
+	// them after the file and environment.
+	configPath := fs.String("config", getenv("TRIFLE_CONFIG"), "path to a JSON config file")
+	flagValues := make(map[string]string)
+	for _, f := range flagFields {
+		record := func(v string) error {
+			flagValues[f.name] = v
+			return nil
+		}
+		if f.isBool {
+			fs.BoolFunc(f.name, f.usage, record)
+		} else {
+			fs.Func(f.name, f.usage, record)
+		}
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *configPath != "" {
+		if err := c.loadFile(*configPath); err != nil {
+			return err
+		}
+	}
+
+	for _, f := range fields {
+		if f.env == "" {
+			continue
+		}
+		if v := getenv(f.env); v != "" {
+			if err := f.set(c, v); err != nil {
+				return fmt.Errorf("invalid %s: %w", f.env, err)
+			}
+		}
+	}
+
+	for _, f := range flagFields {
+		if v, ok := flagValues[f.name]; ok {
+			if err := f.set(c, v); err != nil {
+				return fmt.Errorf("invalid -%s: %w", f.name, err)
+			}
+		}
+	}
+
+	if c.RedirectURL == "" {
+		c.RedirectURL = fmt.Sprintf("http://localhost:%s/auth/callback", c.Port)
+	}
+	c.Production = strings.HasPrefix(c.RedirectURL, "https://")
+
+	return nil
+}
+
+// loadFile applies values from a JSON config file
+func (c *Config) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var values map[string]json.RawMessage
+	if err := json.Unmarshal(data, &values); err != nil {
+		return fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+
+	byName := make(map[string]field, len(fields))
+	for _, f := range fields {
+		byName[f.name] = f
+	}
+
+	for key, raw := range values {
+		f, ok := byName[key]
+		if !ok {
+			return fmt.Errorf("config file %s: unknown key %q", path, key)
+		}
+
+		// Accept both JSON strings and bare numbers/booleans
+		var v string
+		if err := json.Unmarshal(raw, &v); err != nil {
+			v = string(raw)
+		}
+		if err := f.set(c, v); err != nil {
+			return fmt.Errorf("config file %s: invalid %q: %w", path, key, err)
+		}
+	}
+
+	return nil
+}
+
+// Validate checks that the configuration is complete and sensible
+func (c *Config) Validate() error {
+	var errs []error
+
+	if port, err := strconv.Atoi(c.Port); err != nil || port < 1 || port > 65535 {
+		errs = append(errs, fmt.Errorf("port must be a number between 1 and 65535, got %q", c.Port))
+	}
+	if c.DataDir == "" {
+		errs = append(errs, errors.New("data-dir must not be empty"))
+	}
+	if u, err := url.Parse(c.RedirectURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		errs = append(errs, fmt.Errorf("redirect-url must be an absolute http(s) URL, got %q", c.RedirectURL))
+	}
+	if c.GoogleClientID == "" {
+		errs = append(errs, errors.New("GOOGLE_CLIENT_ID not set"))
+	}
+	if c.GoogleClientSecret == "" {
+		errs = append(errs, errors.New("GOOGLE_CLIENT_SECRET not set"))
+	}
+	for _, d := range []struct {
+		name  string
+		value time.Duration
+	}{
+		{"session-lifetime", c.SessionLifetime},
+		{"read-timeout", c.ReadTimeout},
+		{"read-header-timeout", c.ReadHeaderTimeout},
+		{"write-timeout", c.WriteTimeout},
+		{"idle-timeout", c.IdleTimeout},
+		{"upload-timeout", c.UploadTimeout},
+		{"shutdown-timeout", c.ShutdownTimeout},
+	} {
+		if d.value <= 0 {
+			errs = append(errs, fmt.Errorf("%s must be positive, got %s", d.name, d.value))
+		}
+	}
+
+	for _, origin := range c.CORSOrigins {
+		if origin == "*" {
+			if c.CORSCredentials {
+				errs = append(errs, errors.New("cors-origins may not contain \"*\" when cors-credentials is enabled"))
+			}
+			continue
+		}
+		if u, err := url.Parse(origin); err != nil || u.Scheme == "" || u.Host == "" || (u.Path != "" && u.Path != "/") {
+			errs = append(errs, fmt.Errorf("cors-origins entries must look like scheme://host[:port], got %q", origin))
+		}
+	}
+
+	if c.MaxHeaderBytes <= 0 {
+		errs = append(errs, fmt.Errorf("max-header-bytes must be positive, got %d", c.MaxHeaderBytes))
+	}
+	if c.MaxValueMB <= 0 {
+		errs = append(errs, fmt.Errorf("max-value-mb must be positive, got %d", c.MaxValueMB))
+	}
+
+	if c.AccessLogFormat != "text" && c.AccessLogFormat != "combined" {
+		errs = append(errs, fmt.Errorf("access-log-format must be text or combined, got %q", c.AccessLogFormat))
+	}
+	if c.AccessLogMaxSizeMB < 0 || c.AccessLogMaxAge < 0 || c.AccessLogKeep < 0 {
+		errs = append(errs, errors.New("access-log-max-size-mb, access-log-max-age, and access-log-keep must not be negative"))
+	}
+
+	for _, proxy := range c.TrustedProxies {
+		if _, err := netip.ParsePrefix(proxy); err == nil {
+			continue
+		}
+		if _, err := netip.ParseAddr(proxy); err != nil {
+			errs = append(errs, fmt.Errorf("trusted-proxies entries must be IPs or CIDRs, got %q", proxy))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Print writes the effective configuration with secrets redacted
+func (c *Config) Print(w io.Writer) {
+	for _, f := range fields {
+		v := f.get(c)
+		if f.secret && v != "" {
+			v = "[redacted]"
+		}
+		fmt.Fprintf(w, "%-22s %s\n", f.name, v)
+	}
+	fmt.Fprintf(w, "%-22s %t\n", "production", c.Production)
+}
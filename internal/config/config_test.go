@@ -0,0 +1,376 @@
+package config
+
+import (
+	"bytes"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// env returns a getenv func backed by a map
+func env(values map[string]string) func(string) string {
+	return func(key string) string {
+		return values[key]
+	}
+}
+
+// credentials is the minimal environment needed to pass validation
+var credentials = map[string]string{
+	"GOOGLE_CLIENT_ID":     "id",
+	"GOOGLE_CLIENT_SECRET": "secret",
+}
+
+func withCredentials(extra map[string]string) map[string]string {
+	values := map[string]string{}
+	for k, v := range credentials {
+		values[k] = v
+	}
+	for k, v := range extra {
+		values[k] = v
+	}
+	return values
+}
+
+func writeConfigFile(t *testing.T, contents string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "trifle.json")
+	if err := os.WriteFile(path, []byte(contents), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	return path
+}
+
+func TestLoad_Defaults(t *testing.T) {
+	cfg, err := Load(nil, env(credentials))
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	if cfg.Port != "3000" {
+		t.Errorf("Expected default port 3000, got %q", cfg.Port)
+	}
+	if cfg.DataDir != "./data" {
+		t.Errorf("Expected default data dir ./data, got %q", cfg.DataDir)
+	}
+	if cfg.RedirectURL != "http://localhost:3000/auth/callback" {
+		t.Errorf("Unexpected default redirect URL: %q", cfg.RedirectURL)
+	}
+	if cfg.Production {
+		t.Errorf("Expected non-production for http redirect URL")
+	}
+	if !cfg.LegacyKeys {
+		t.Errorf("Expected legacy keys to be allowed by default")
+	}
+}
+
+func TestLoad_Precedence(t *testing.T) {
+	path := writeConfigFile(t, `{"port": 4000, "data-dir": "/from/file", "write-timeout": "20s", "idle-timeout": "90s"}`)
+
+	cfg, err := Load(
+		[]string{"-config", path, "-port", "6000"},
+		env(withCredentials(map[string]string{
+			"PORT":          "5000",
+			"WRITE_TIMEOUT": "25s",
+		})),
+	)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	// Flag beats env and file
+	if cfg.Port != "6000" {
+		t.Errorf("Expected flag port 6000, got %q", cfg.Port)
+	}
+	// Env beats file
+	if cfg.WriteTimeout != 25*time.Second {
+		t.Errorf("Expected env write timeout 25s, got %s", cfg.WriteTimeout)
+	}
+	// File beats default
+	if cfg.DataDir != "/from/file" {
+		t.Errorf("Expected file data dir, got %q", cfg.DataDir)
+	}
+	if cfg.IdleTimeout != 90*time.Second {
+		t.Errorf("Expected file idle timeout 90s, got %s", cfg.IdleTimeout)
+	}
+	// Untouched values keep their defaults
+	if cfg.ReadTimeout != 15*time.Second {
+		t.Errorf("Expected default read timeout, got %s", cfg.ReadTimeout)
+	}
+	// Default redirect URL follows the effective port
+	if cfg.RedirectURL != "http://localhost:6000/auth/callback" {
+		t.Errorf("Unexpected redirect URL: %q", cfg.RedirectURL)
+	}
+}
+
+func TestLoad_ConfigPathFromEnv(t *testing.T) {
+	path := writeConfigFile(t, `{"port": "7000"}`)
+
+	cfg, err := Load(nil, env(withCredentials(map[string]string{"TRIFLE_CONFIG": path})))
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if cfg.Port != "7000" {
+		t.Errorf("Expected port from TRIFLE_CONFIG file, got %q", cfg.Port)
+	}
+}
+
+func TestLoad_ProductionFromRedirectURL(t *testing.T) {
+	cfg, err := Load(
+		[]string{"-redirect-url", "https://trifling.org/auth/callback"},
+		env(credentials),
+	)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if !cfg.Production {
+		t.Errorf("Expected production mode for https redirect URL")
+	}
+}
+
+func TestLoad_Validation(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		env     map[string]string
+		wantErr string
+	}{
+		{
+			name:    "missing client ID",
+			env:     map[string]string{"GOOGLE_CLIENT_SECRET": "secret"},
+			wantErr: "GOOGLE_CLIENT_ID not set",
+		},
+		{
+			name:    "missing client secret",
+			env:     map[string]string{"GOOGLE_CLIENT_ID": "id"},
+			wantErr: "GOOGLE_CLIENT_SECRET not set",
+		},
+		{
+			name:    "non-numeric port",
+			args:    []string{"-port", "http"},
+			env:     credentials,
+			wantErr: "port must be a number",
+		},
+		{
+			name:    "port out of range",
+			args:    []string{"-port", "70000"},
+			env:     credentials,
+			wantErr: "port must be a number",
+		},
+		{
+			name:    "relative redirect URL",
+			args:    []string{"-redirect-url", "/auth/callback"},
+			env:     credentials,
+			wantErr: "redirect-url must be an absolute",
+		},
+		{
+			name:    "zero timeout",
+			args:    []string{"-write-timeout", "0s"},
+			env:     credentials,
+			wantErr: "write-timeout must be positive",
+		},
+		{
+			name:    "unparseable duration in env",
+			env:     withCredentials(map[string]string{"READ_TIMEOUT": "soon"}),
+			wantErr: "invalid READ_TIMEOUT",
+		},
+		{
+			name:    "wildcard CORS origin with credentials",
+			args:    []string{"-cors-origins", "*", "-cors-credentials"},
+			env:     credentials,
+			wantErr: "may not contain",
+		},
+		{
+			name:    "CORS origin with a path",
+			args:    []string{"-cors-origins", "https://client.example.com/app"},
+			env:     credentials,
+			wantErr: "cors-origins entries",
+		},
+		{
+			name:    "zero max header bytes",
+			args:    []string{"-max-header-bytes", "0"},
+			env:     credentials,
+			wantErr: "max-header-bytes must be positive",
+		},
+		{
+			name:    "zero max value",
+			args:    []string{"-max-value-mb", "0"},
+			env:     credentials,
+			wantErr: "max-value-mb must be positive",
+		},
+		{
+			name:    "zero read header timeout",
+			env:     withCredentials(map[string]string{"READ_HEADER_TIMEOUT": "0s"}),
+			wantErr: "read-header-timeout must be positive",
+		},
+		{
+			name:    "unknown access log format",
+			env:     withCredentials(map[string]string{"ACCESS_LOG_FORMAT": "apache"}),
+			wantErr: "access-log-format must be text or combined",
+		},
+		{
+			name:    "negative access log retention",
+			args:    []string{"-access-log-keep", "-1"},
+			env:     credentials,
+			wantErr: "must not be negative",
+		},
+		{
+			name:    "unparseable int",
+			args:    []string{"-access-log-max-size-mb", "lots"},
+			env:     credentials,
+			wantErr: "invalid -access-log-max-size-mb",
+		},
+		{
+			name:    "bad trusted proxy",
+			env:     withCredentials(map[string]string{"TRUSTED_PROXIES": "10.0.0.0/8,proxy.internal"}),
+			wantErr: "trusted-proxies entries must be IPs or CIDRs",
+		},
+		{
+			name:    "unparseable bool",
+			env:     withCredentials(map[string]string{"CORS_CREDENTIALS": "sometimes"}),
+			wantErr: "invalid CORS_CREDENTIALS",
+		},
+		{
+			name:    "stray argument",
+			args:    []string{"serve"},
+			env:     credentials,
+			wantErr: "unexpected arguments",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Load(tt.args, env(tt.env))
+			if err == nil {
+				t.Fatalf("Expected error containing %q but got success", tt.wantErr)
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got: %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestLoad_ReportsAllValidationErrors(t *testing.T) {
+	_, err := Load([]string{"-port", "x"}, env(nil))
+	if err == nil {
+		t.Fatal("Expected validation error")
+	}
+	for _, want := range []string{"port", "GOOGLE_CLIENT_ID", "GOOGLE_CLIENT_SECRET"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected error to mention %q, got: %v", want, err)
+		}
+	}
+}
+
+func TestLoad_ConfigFileErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		contents string
+		wantErr  string
+	}{
+		{name: "unknown key", contents: `{"prot": "3000"}`, wantErr: `unknown key "prot"`},
+		{name: "malformed JSON", contents: `{"port": `, wantErr: "failed to parse config file"},
+		{name: "bad duration", contents: `{"read-timeout": "forever"}`, wantErr: `invalid "read-timeout"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeConfigFile(t, tt.contents)
+			_, err := Load([]string{"-config", path}, env(credentials))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got: %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestPrint_RedactsSecrets(t *testing.T) {
+	cfg, err := Load([]string{"-print-config"}, env(credentials))
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if !cfg.PrintConfig {
+		t.Errorf("Expected PrintConfig to be set")
+	}
+
+	var buf bytes.Buffer
+	cfg.Print(&buf)
+	out := buf.String()
+
+	if strings.Contains(out, "secret") && !strings.Contains(out, "[redacted]") {
+		t.Errorf("Expected client secret to be redacted, got:\n%s", out)
+	}
+	if strings.Contains(out, " secret\n") {
+		t.Errorf("Client secret leaked in output:\n%s", out)
+	}
+	if !strings.Contains(out, "google-client-id       id\n") {
+		t.Errorf("Expected client ID in output, got:\n%s", out)
+	}
+}
+
+func TestLoad_PrintConfigSkipsValidation(t *testing.T) {
+	// Missing credentials and a bad redirect URL are what -print-config
+	// helps debug, so Load leaves them for the caller to report
+	cfg, err := Load([]string{"-print-config", "-redirect-url", "/auth/callback"}, env(nil))
+	if err != nil {
+		t.Fatalf("Expected -print-config to load an invalid configuration, got: %v", err)
+	}
+	var buf bytes.Buffer
+	cfg.Print(&buf)
+	if !strings.Contains(buf.String(), "/auth/callback") {
+		t.Errorf("Expected the redirect URL in output, got:\n%s", buf.String())
+	}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "GOOGLE_CLIENT_ID") {
+		t.Errorf("Expected Validate to still report missing credentials, got: %v", err)
+	}
+}
+
+func TestLoad_AdminEmails(t *testing.T) {
+	cfg, err := Load(nil, env(withCredentials(map[string]string{
+		"ADMIN_EMAILS": " alice@example.com, ,bob@example.com ",
+	})))
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	want := []string{"alice@example.com", "bob@example.com"}
+	if strings.Join(cfg.AdminEmails, "|") != strings.Join(want, "|") {
+		t.Errorf("Expected admin emails %v, got %v", want, cfg.AdminEmails)
+	}
+}
+
+func TestLoadCommand(t *testing.T) {
+	path := writeConfigFile(t, `{"data-dir": "/from/file", "port": "9000"}`)
+
+	fs := flag.NewFlagSet("trifle allowlist add", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	dryRun := fs.Bool("dry-run", false, "")
+
+	// No OAuth credentials: admin commands don't need them
+	cfg, err := LoadCommand(fs, []string{"-config", path, "-dry-run", "alice@example.com"}, env(map[string]string{
+		"DATA_DIR": "/from/env",
+	}))
+	if err != nil {
+		t.Fatalf("LoadCommand failed: %v", err)
+	}
+	if cfg.DataDir != "/from/env" {
+		t.Errorf("Expected env to override file, got data dir %q", cfg.DataDir)
+	}
+	if !*dryRun {
+		t.Errorf("Expected command-specific flag to be parsed")
+	}
+	if got := strings.Join(fs.Args(), " "); got != "alice@example.com" {
+		t.Errorf("Expected positional args to be left, got %q", got)
+	}
+
+	// Server-only flags aren't accepted
+	fs = flag.NewFlagSet("trifle allowlist list", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	if _, err := LoadCommand(fs, []string{"-port", "8080"}, env(nil)); err == nil {
+		t.Errorf("Expected -port to be rejected for admin commands")
+	}
+}
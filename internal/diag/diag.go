@@ -0,0 +1,77 @@
+// Package diag serves runtime diagnostics: net/http/pprof profiles and a
+// snapshot of internal counters. Everything here is admin-only; callers
+// must put the handler behind an admin check.
+package diag
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Var reports the current value of an internal counter
+type Var func() any
+
+// Handler returns a handler for /debug/pprof/ and /debug/vars. vars adds
+// app-specific values (session count, ...) to the runtime stats in
+// /debug/vars.
+func Handler(vars map[string]Var) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.Handle("/debug/pprof/profile", extendWriteDeadline(http.HandlerFunc(pprof.Profile)))
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.Handle("/debug/pprof/trace", extendWriteDeadline(http.HandlerFunc(pprof.Trace)))
+	mux.HandleFunc("/debug/vars", handleVars(vars))
+	return mux
+}
+
+// extendWriteDeadline lifts the server's WriteTimeout for profiles that
+// sample for ?seconds=N, which pprof otherwise rejects when N exceeds it
+func extendWriteDeadline(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seconds, err := strconv.Atoi(r.FormValue("seconds"))
+		if err != nil || seconds <= 0 {
+			seconds = 30 // pprof's default
+		}
+		// Errors mean the writer doesn't support deadlines; nothing to lift
+		http.NewResponseController(w).SetWriteDeadline(time.Now().Add(time.Duration(seconds)*time.Second + 10*time.Second))
+		next.ServeHTTP(w, r)
+	})
+}
+
+// handleVars serves runtime stats and vars as one JSON object
+func handleVars(vars map[string]Var) http.HandlerFunc {
+	names := make([]string, 0, len(vars))
+	for name := range vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		var mem runtime.MemStats
+		runtime.ReadMemStats(&mem)
+
+		snapshot := map[string]any{
+			"goroutines":   runtime.NumGoroutine(),
+			"heap_alloc":   mem.HeapAlloc,
+			"heap_objects": mem.HeapObjects,
+			"heap_sys":     mem.HeapSys,
+			"num_gc":       mem.NumGC,
+			"gc_pause_ns":  mem.PauseTotalNs,
+		}
+		for _, name := range names {
+			snapshot[name] = vars[name]()
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		enc.Encode(snapshot)
+	}
+}
@@ -0,0 +1,87 @@
+package diag
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zellyn/trifle/internal/auth"
+)
+
+func TestHandler_AdminGated(t *testing.T) {
+	sessionMgr := auth.NewSessionManager(false, time.Hour)
+	rec := httptest.NewRecorder()
+	session, err := sessionMgr.GetOrCreateSession(httptest.NewRequest(http.MethodGet, "/", nil), rec)
+	if err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+	session.Email = "user@example.com"
+	session.Authenticated = true
+	userCookie := rec.Result().Cookies()[0]
+
+	gate := auth.NewAdminGate(sessionMgr, []string{"admin@example.com"}, "s3cret")
+	handler := gate.Require(Handler(nil))
+
+	tests := []struct {
+		name       string
+		cookie     *http.Cookie
+		auth       string
+		wantStatus int
+	}{
+		{name: "anonymous", wantStatus: http.StatusUnauthorized},
+		{name: "non-admin session", cookie: userCookie, wantStatus: http.StatusForbidden},
+		{name: "wrong token", auth: "Bearer nope", wantStatus: http.StatusForbidden},
+		{name: "admin token", auth: "Bearer s3cret", wantStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/debug/pprof/goroutine?debug=1", nil)
+			if tt.cookie != nil {
+				req.AddCookie(tt.cookie)
+			}
+			if tt.auth != "" {
+				req.Header.Set("Authorization", tt.auth)
+			}
+
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("Expected %d, got %d", tt.wantStatus, rec.Code)
+			}
+			if tt.wantStatus == http.StatusOK && !strings.Contains(rec.Body.String(), "goroutine profile:") {
+				t.Errorf("Expected a goroutine profile, got %q", rec.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK && strings.Contains(rec.Body.String(), "goroutine") {
+				t.Error("Expected no profile data for a rejected request")
+			}
+		})
+	}
+}
+
+func TestHandler_Vars(t *testing.T) {
+	handler := Handler(map[string]Var{
+		"sessions": func() any { return 3 },
+	})
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/vars", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", rec.Code)
+	}
+	var vars map[string]any
+	if err := json.NewDecoder(rec.Body).Decode(&vars); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if vars["sessions"] != float64(3) {
+		t.Errorf("Expected sessions 3, got %v", vars["sessions"])
+	}
+	if n, ok := vars["goroutines"].(float64); !ok || n < 1 {
+		t.Errorf("Expected a goroutine count, got %v", vars["goroutines"])
+	}
+}
@@ -0,0 +1,75 @@
+// Package health provides liveness and readiness endpoints for probes.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// checkTimeout bounds how long a single readiness check may take
+const checkTimeout = 2 * time.Second
+
+// Check reports whether a component is ready to serve traffic
+type Check func(ctx context.Context) error
+
+// ReadyResponse is the JSON body returned by the readiness endpoint
+type ReadyResponse struct {
+	Status      string            `json:"status"`
+	Failing     map[string]string `json:"failing,omitempty"`
+	Maintenance bool              `json:"maintenance,omitempty"`
+}
+
+// HandleHealthz reports that the process is up and serving HTTP
+func HandleHealthz(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Write([]byte("ok\n"))
+}
+
+// HandleReadyz runs every check and returns 200 if all pass, or 503 with
+// the failing components otherwise. The response also reports whether
+// maintenance mode is on (maintenance may be nil); an instance in
+// maintenance still serves reads, so it stays ready.
+func HandleReadyz(checks map[string]Check, maintenance func() bool) http.HandlerFunc {
+	// Run checks in a stable order so logs are predictable
+	names := make([]string, 0, len(checks))
+	for name := range checks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		resp := ReadyResponse{Status: "ok"}
+		if maintenance != nil {
+			resp.Maintenance = maintenance()
+		}
+
+		for _, name := range names {
+			ctx, cancel := context.WithTimeout(r.Context(), checkTimeout)
+			err := checks[name](ctx)
+			cancel()
+			if err != nil {
+				slog.Warn("Readiness check failed", "component", name, "error", err)
+				if resp.Failing == nil {
+					resp.Failing = make(map[string]string)
+				}
+				resp.Failing[name] = err.Error()
+			}
+		}
+
+		status := http.StatusOK
+		if len(resp.Failing) > 0 {
+			resp.Status = "unavailable"
+			status = http.StatusServiceUnavailable
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(resp)
+	}
+}
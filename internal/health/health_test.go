@@ -0,0 +1,112 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHandleHealthz(t *testing.T) {
+	rec := httptest.NewRecorder()
+	HandleHealthz(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected 200, got %d", rec.Code)
+	}
+}
+
+func TestHandleReadyz(t *testing.T) {
+	ok := func(ctx context.Context) error { return nil }
+	failing := func(ctx context.Context) error { return errors.New("data directory not writable") }
+
+	tests := []struct {
+		name        string
+		checks      map[string]Check
+		wantStatus  int
+		wantFailing []string
+	}{
+		{
+			name:       "no checks",
+			checks:     map[string]Check{},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "all passing",
+			checks:     map[string]Check{"kv": ok, "other": ok},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:        "one failing",
+			checks:      map[string]Check{"kv": failing, "other": ok},
+			wantStatus:  http.StatusServiceUnavailable,
+			wantFailing: []string{"kv"},
+		},
+		{
+			name:        "all failing",
+			checks:      map[string]Check{"kv": failing, "other": failing},
+			wantStatus:  http.StatusServiceUnavailable,
+			wantFailing: []string{"kv", "other"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			HandleReadyz(tt.checks, nil)(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("Expected %d, got %d", tt.wantStatus, rec.Code)
+			}
+
+			var resp ReadyResponse
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if len(resp.Failing) != len(tt.wantFailing) {
+				t.Errorf("Expected failing %v, got %v", tt.wantFailing, resp.Failing)
+			}
+			for _, name := range tt.wantFailing {
+				if resp.Failing[name] == "" {
+					t.Errorf("Expected %q to be reported as failing", name)
+				}
+			}
+		})
+	}
+}
+
+func TestHandleReadyz_CheckGetsDeadline(t *testing.T) {
+	checks := map[string]Check{
+		"slow": func(ctx context.Context) error {
+			if _, ok := ctx.Deadline(); !ok {
+				return errors.New("no deadline")
+			}
+			return nil
+		},
+	}
+
+	rec := httptest.NewRecorder()
+	HandleReadyz(checks, nil)(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected checks to run with a deadline, got %d: %s", rec.Code, rec.Body)
+	}
+}
+
+func TestHandleReadyz_Maintenance(t *testing.T) {
+	rec := httptest.NewRecorder()
+	HandleReadyz(map[string]Check{}, func() bool { return true })(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected maintenance mode to stay ready, got %d", rec.Code)
+	}
+	var resp ReadyResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if !resp.Maintenance {
+		t.Error("Expected maintenance to be reported")
+	}
+}
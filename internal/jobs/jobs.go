@@ -0,0 +1,258 @@
+// Package jobs runs periodic background work (cleanup, sweeps, maintenance)
+// on a shared scheduler that stops cleanly at shutdown.
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Func is the work done by a job. ctx is cancelled when the scheduler stops.
+type Func func(ctx context.Context) error
+
+// Clock abstracts time so tests can control scheduling
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time                         { return time.Now() }
+func (realClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+// Status reports the state of one job
+type Status struct {
+	Name         string        `json:"name"`
+	Interval     time.Duration `json:"interval_ns"`
+	Running      bool          `json:"running"`
+	Runs         int           `json:"runs"`
+	Skipped      int           `json:"skipped"`
+	LastRun      time.Time     `json:"last_run,omitzero"`
+	LastDuration time.Duration `json:"last_duration_ns"`
+	LastError    string        `json:"last_error,omitempty"`
+}
+
+type job struct {
+	name     string
+	interval time.Duration
+	jitter   time.Duration
+	fn       Func
+
+	// Guarded by Scheduler.mu
+	running bool
+	status  Status
+}
+
+// Scheduler runs registered jobs at their intervals. Runs of the same job
+// never overlap: a tick that arrives while the previous run is still going
+// is skipped. Panics in a job are recovered and recorded as errors.
+type Scheduler struct {
+	clock  Clock
+	logger *slog.Logger
+	jitter func(max time.Duration) time.Duration
+
+	mu      sync.Mutex
+	jobs    map[string]*job
+	ctx     context.Context
+	cancel  context.CancelFunc
+	started bool
+	stopped bool
+	wg      sync.WaitGroup
+}
+
+// New creates a scheduler. Jobs start running when Start is called.
+func New() *Scheduler {
+	return &Scheduler{
+		clock:  realClock{},
+		logger: slog.Default(),
+		jitter: func(max time.Duration) time.Duration {
+			return rand.N(max)
+		},
+		jobs: make(map[string]*job),
+	}
+}
+
+// Register adds a job that runs every interval plus a random delay in
+// [0, jitter), so jobs on many instances don't run in lockstep. Jobs
+// registered after Start begin immediately.
+func (s *Scheduler) Register(name string, interval, jitter time.Duration, fn Func) error {
+	if interval <= 0 {
+		return fmt.Errorf("job %s: interval must be positive", name)
+	}
+	if jitter < 0 {
+		return fmt.Errorf("job %s: jitter must not be negative", name)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.jobs[name]; exists {
+		return fmt.Errorf("job %s already registered", name)
+	}
+	if s.stopped {
+		return fmt.Errorf("job %s: scheduler stopped", name)
+	}
+
+	j := &job{
+		name:     name,
+		interval: interval,
+		jitter:   jitter,
+		fn:       fn,
+		status:   Status{Name: name, Interval: interval},
+	}
+	s.jobs[name] = j
+	if s.started {
+		s.launch(j)
+	}
+	return nil
+}
+
+// Start begins running registered jobs. Their contexts derive from ctx.
+func (s *Scheduler) Start(ctx context.Context) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.started || s.stopped {
+		return
+	}
+	s.started = true
+	s.ctx, s.cancel = context.WithCancel(ctx)
+	for _, j := range s.jobs {
+		s.launch(j)
+	}
+}
+
+// Stop cancels all jobs and waits for running ones to return, or for ctx
+// to expire
+func (s *Scheduler) Stop(ctx context.Context) error {
+	s.mu.Lock()
+	s.stopped = true
+	if s.cancel != nil {
+		s.cancel()
+	}
+	s.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("jobs still running: %w", ctx.Err())
+	}
+}
+
+// Status returns the state of every job, sorted by name
+func (s *Scheduler) Status() []Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	statuses := make([]Status, 0, len(s.jobs))
+	for _, j := range s.jobs {
+		st := j.status
+		st.Running = j.running
+		statuses = append(statuses, st)
+	}
+	sort.Slice(statuses, func(i, k int) bool { return statuses[i].Name < statuses[k].Name })
+	return statuses
+}
+
+// HandleStatus serves the status of every job as JSON
+func (s *Scheduler) HandleStatus(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(s.Status())
+}
+
+// launch starts the ticker loop for j. s.mu must be held.
+func (s *Scheduler) launch(j *job) {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.loop(j)
+	}()
+}
+
+// loop waits out each interval and triggers a run
+func (s *Scheduler) loop(j *job) {
+	for {
+		wait := j.interval
+		if j.jitter > 0 {
+			wait += s.jitter(j.jitter)
+		}
+
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-s.clock.After(wait):
+		}
+
+		s.trigger(j)
+	}
+}
+
+// trigger starts a run of j unless one is already in progress
+func (s *Scheduler) trigger(j *job) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.ctx.Err() != nil {
+		return
+	}
+	if j.running {
+		j.status.Skipped++
+		s.logger.Warn("Skipping job run, previous run still in progress", "job", j.name)
+		return
+	}
+	j.running = true
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.run(j)
+	}()
+}
+
+// run executes j once, recording the outcome
+func (s *Scheduler) run(j *job) {
+	start := s.clock.Now()
+	err := s.call(j)
+	duration := s.clock.Now().Sub(start)
+
+	s.mu.Lock()
+	j.running = false
+	j.status.Runs++
+	j.status.LastRun = start
+	j.status.LastDuration = duration
+	j.status.LastError = ""
+	if err != nil {
+		j.status.LastError = err.Error()
+	}
+	s.mu.Unlock()
+
+	if err != nil {
+		s.logger.Error("Job failed", "job", j.name, "duration", duration, "error", err)
+	}
+}
+
+// call runs the job function, converting a panic into an error
+func (s *Scheduler) call(j *job) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return j.fn(s.ctx)
+}
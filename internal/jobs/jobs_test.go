@@ -0,0 +1,308 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeClock fires After channels only when the test advances time
+type fakeClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	waiters []waiter
+}
+
+type waiter struct {
+	deadline time.Time
+	ch       chan time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ch := make(chan time.Time, 1)
+	c.waiters = append(c.waiters, waiter{deadline: c.now.Add(d), ch: ch})
+	return ch
+}
+
+// Advance moves time forward, firing any waiters whose deadline has passed
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+	remaining := c.waiters[:0]
+	for _, w := range c.waiters {
+		if !w.deadline.After(c.now) {
+			w.ch <- c.now
+		} else {
+			remaining = append(remaining, w)
+		}
+	}
+	c.waiters = remaining
+}
+
+// waitForWaiters blocks until n goroutines are waiting on the clock
+func (c *fakeClock) waitForWaiters(t *testing.T, n int) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		c.mu.Lock()
+		got := len(c.waiters)
+		c.mu.Unlock()
+		if got >= n {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("Timed out waiting for %d clock waiters", n)
+}
+
+func newTestScheduler(clock Clock) *Scheduler {
+	s := New()
+	s.clock = clock
+	s.logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	return s
+}
+
+// waitFor polls cond until it is true or the test times out
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if cond() {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("Timed out waiting for %s", what)
+}
+
+func TestScheduler_RunsAtInterval(t *testing.T) {
+	clock := newFakeClock()
+	s := newTestScheduler(clock)
+
+	var runs atomic.Int32
+	if err := s.Register("count", time.Minute, 0, func(ctx context.Context) error {
+		runs.Add(1)
+		return nil
+	}); err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+	s.Start(context.Background())
+	defer s.Stop(context.Background())
+
+	clock.waitForWaiters(t, 1)
+	clock.Advance(59 * time.Second)
+	if got := runs.Load(); got != 0 {
+		t.Fatalf("Expected no runs before the interval, got %d", got)
+	}
+
+	for i := 1; i <= 3; i++ {
+		clock.Advance(time.Second)
+		waitFor(t, "job run", func() bool { return runs.Load() == int32(i) })
+		clock.waitForWaiters(t, 1)
+		if i < 3 {
+			clock.Advance(59 * time.Second)
+		}
+	}
+
+	st := s.Status()
+	if len(st) != 1 || st[0].Name != "count" || st[0].Runs != 3 {
+		t.Errorf("Unexpected status %+v", st)
+	}
+}
+
+func TestScheduler_JitterBounds(t *testing.T) {
+	s := New()
+	for i := 0; i < 1000; i++ {
+		d := s.jitter(10 * time.Second)
+		if d < 0 || d >= 10*time.Second {
+			t.Fatalf("Jitter %v outside [0, 10s)", d)
+		}
+	}
+
+	// The scheduler waits interval + jitter before each run
+	clock := newFakeClock()
+	s = newTestScheduler(clock)
+	s.jitter = func(max time.Duration) time.Duration { return max - time.Second }
+
+	var runs atomic.Int32
+	s.Register("jittered", time.Minute, 10*time.Second, func(ctx context.Context) error {
+		runs.Add(1)
+		return nil
+	})
+	s.Start(context.Background())
+	defer s.Stop(context.Background())
+
+	clock.waitForWaiters(t, 1)
+	clock.Advance(68 * time.Second)
+	if got := runs.Load(); got != 0 {
+		t.Fatalf("Expected no run before interval+jitter, got %d", got)
+	}
+	clock.Advance(time.Second)
+	waitFor(t, "jittered run", func() bool { return runs.Load() == 1 })
+}
+
+func TestScheduler_SkipsOverlappingRuns(t *testing.T) {
+	clock := newFakeClock()
+	s := newTestScheduler(clock)
+
+	release := make(chan struct{})
+	var runs atomic.Int32
+	s.Register("slow", time.Minute, 0, func(ctx context.Context) error {
+		runs.Add(1)
+		<-release
+		return nil
+	})
+	s.Start(context.Background())
+	defer s.Stop(context.Background())
+
+	clock.waitForWaiters(t, 1)
+	clock.Advance(time.Minute)
+	waitFor(t, "first run", func() bool { return runs.Load() == 1 })
+
+	// Two more ticks while the first run is still going
+	for i := 0; i < 2; i++ {
+		clock.waitForWaiters(t, 1)
+		clock.Advance(time.Minute)
+	}
+	waitFor(t, "skips", func() bool { return s.Status()[0].Skipped == 2 })
+
+	if !s.Status()[0].Running {
+		t.Errorf("Expected job to be reported as running")
+	}
+	close(release)
+	waitFor(t, "run to finish", func() bool { return !s.Status()[0].Running })
+	if got := runs.Load(); got != 1 {
+		t.Errorf("Expected overlapping ticks to be skipped, got %d runs", got)
+	}
+}
+
+func TestScheduler_RecordsErrorsAndPanics(t *testing.T) {
+	clock := newFakeClock()
+	s := newTestScheduler(clock)
+
+	s.Register("fails", time.Minute, 0, func(ctx context.Context) error {
+		return errors.New("disk full")
+	})
+	s.Register("panics", time.Minute, 0, func(ctx context.Context) error {
+		panic("boom")
+	})
+	s.Start(context.Background())
+	defer s.Stop(context.Background())
+
+	clock.waitForWaiters(t, 2)
+	clock.Advance(time.Minute)
+	waitFor(t, "both runs", func() bool {
+		st := s.Status()
+		return st[0].Runs == 1 && st[1].Runs == 1
+	})
+
+	st := s.Status()
+	if st[0].Name != "fails" || st[0].LastError != "disk full" {
+		t.Errorf("Unexpected status for failing job: %+v", st[0])
+	}
+	if st[1].Name != "panics" || st[1].LastError != "panic: boom" {
+		t.Errorf("Unexpected status for panicking job: %+v", st[1])
+	}
+	if st[0].LastRun.IsZero() {
+		t.Errorf("Expected LastRun to be set")
+	}
+
+	// The scheduler keeps going after a panic
+	clock.waitForWaiters(t, 2)
+	clock.Advance(time.Minute)
+	waitFor(t, "second runs", func() bool { return s.Status()[1].Runs == 2 })
+}
+
+func TestScheduler_Stop(t *testing.T) {
+	clock := newFakeClock()
+	s := newTestScheduler(clock)
+
+	started := make(chan struct{})
+	var cancelled atomic.Bool
+	s.Register("waits", time.Minute, 0, func(ctx context.Context) error {
+		close(started)
+		<-ctx.Done()
+		cancelled.Store(true)
+		return ctx.Err()
+	})
+	s.Register("idle", time.Hour, 0, func(ctx context.Context) error { return nil })
+	s.Start(context.Background())
+
+	clock.waitForWaiters(t, 2)
+	clock.Advance(time.Minute)
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := s.Stop(ctx); err != nil {
+		t.Fatalf("Stop failed: %v", err)
+	}
+	if !cancelled.Load() {
+		t.Errorf("Expected running job's context to be cancelled")
+	}
+
+	if err := s.Register("late", time.Minute, 0, func(ctx context.Context) error { return nil }); err == nil {
+		t.Errorf("Expected Register after Stop to fail")
+	}
+}
+
+func TestScheduler_StopDeadline(t *testing.T) {
+	clock := newFakeClock()
+	s := newTestScheduler(clock)
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	s.Register("stubborn", time.Minute, 0, func(ctx context.Context) error {
+		close(started)
+		<-release
+		return nil
+	})
+	s.Start(context.Background())
+
+	clock.waitForWaiters(t, 1)
+	clock.Advance(time.Minute)
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := s.Stop(ctx); err == nil {
+		t.Errorf("Expected Stop to report a job that ignores cancellation")
+	}
+	close(release)
+}
+
+func TestScheduler_RegisterValidation(t *testing.T) {
+	s := New()
+	noop := func(ctx context.Context) error { return nil }
+
+	if err := s.Register("a", 0, 0, noop); err == nil {
+		t.Errorf("Expected error for zero interval")
+	}
+	if err := s.Register("a", time.Minute, -time.Second, noop); err == nil {
+		t.Errorf("Expected error for negative jitter")
+	}
+	if err := s.Register("a", time.Minute, 0, noop); err != nil {
+		t.Errorf("Register failed: %v", err)
+	}
+	if err := s.Register("a", time.Minute, 0, noop); err == nil {
+		t.Errorf("Expected error for duplicate name")
+	}
+}
@@ -0,0 +1,65 @@
+package kv
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/zellyn/trifle/internal/codec"
+)
+
+// maxExistsBatch is the most keys one /kvexists request may ask about
+const maxExistsBatch = 1000
+
+// ExistsResult reports on one key of a /kvexists request. Size and SHA256
+// are set for keys that exist; SHA256 only when the server already knows it.
+type ExistsResult struct {
+	Key       string `json:"key"`
+	Exists    bool   `json:"exists"`
+	Forbidden bool   `json:"forbidden,omitempty"`
+	Size      int64  `json:"size,omitempty"`
+	SHA256    string `json:"sha256,omitempty"`
+}
+
+// HandleExists handles POST /kvexists: given an array of keys (JSON or
+// MessagePack), it reports which exist, so a client can skip uploading
+// content-addressed files the server already has without a HEAD request per
+// file. Keys the user may not read are reported as forbidden rather than
+// failing the batch.
+func (h *Handlers) HandleExists(w http.ResponseWriter, r *http.Request) {
+	var keys []string
+	if err := codec.ReadRequest(w, r, maxExistsBatch*(maxKeyLength+8), &keys); err != nil {
+		codec.RequestError(w, err, "Expected an array of keys")
+		return
+	}
+	if len(keys) > maxExistsBatch {
+		http.Error(w, fmt.Sprintf("At most %d keys per request", maxExistsBatch), http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	results := make([]ExistsResult, len(keys))
+	for i, key := range keys {
+		results[i].Key = key
+		if key == "" || h.checkAuth(r, key) != nil {
+			results[i].Forbidden = true
+			continue
+		}
+
+		size, sum, err := h.store.Stat(key)
+		if err != nil {
+			// Unreadable keys (including malformed ones) are reported
+			// missing; at worst the client uploads again
+			if !strings.Contains(err.Error(), "not found") && !strings.Contains(err.Error(), "invalid key") {
+				slog.Error("Failed to stat key", "error", err, "key", key)
+			}
+			continue
+		}
+		results[i].Exists = true
+		results[i].Size = size
+		results[i].SHA256 = sum
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	codec.WriteResponse(w, r, http.StatusOK, results)
+}
@@ -0,0 +1,93 @@
+package kv
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func existsRequest(t *testing.T, handlers *Handlers, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	r := httptest.NewRequest(http.MethodPost, "/kvexists", strings.NewReader(body))
+	r = r.WithContext(context.WithValue(r.Context(), "user_email", "alice@example.com"))
+	rec := httptest.NewRecorder()
+	handlers.HandleExists(rec, r)
+	return rec
+}
+
+func TestHandleExists(t *testing.T) {
+	store, err := NewStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	handlers := NewHandlers(store)
+
+	store.Put("file/ab/abcd", []byte("blob"))
+	store.Put(testPrefix+"/mine", []byte("hello"))
+	store.Put("domain/example.com/user/bob/secret", []byte("shh"))
+
+	keys := []string{
+		"file/ab/abcd",
+		"file/cd/cdef",
+		testPrefix + "/mine",
+		testPrefix + "/gone",
+		"domain/example.com/user/bob/secret",
+		"other/thing",
+	}
+	body, _ := json.Marshal(keys)
+	rec := existsRequest(t, handlers, string(body))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+
+	var results []ExistsResult
+	if err := json.NewDecoder(rec.Body).Decode(&results); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(results) != len(keys) {
+		t.Fatalf("Expected %d results, got %d", len(keys), len(results))
+	}
+
+	want := []ExistsResult{
+		{Key: "file/ab/abcd", Exists: true, Size: 4, SHA256: hashValue([]byte("blob"))},
+		{Key: "file/cd/cdef"},
+		{Key: testPrefix + "/mine", Exists: true, Size: 5, SHA256: hashValue([]byte("hello"))},
+		{Key: testPrefix + "/gone"},
+		{Key: "domain/example.com/user/bob/secret", Forbidden: true},
+		{Key: "other/thing", Forbidden: true},
+	}
+	for i, w := range want {
+		if results[i] != w {
+			t.Errorf("Key %s: expected %+v, got %+v", w.Key, w, results[i])
+		}
+	}
+}
+
+func TestHandleExists_Rejected(t *testing.T) {
+	store, err := NewStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	handlers := NewHandlers(store)
+
+	tooMany, _ := json.Marshal(make([]string, maxExistsBatch+1))
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+	}{
+		{"not an array", `{"keys": []}`, http.StatusBadRequest},
+		{"too many keys", string(tooMany), http.StatusRequestEntityTooLarge},
+		{"body too large", `["` + strings.Repeat("x", maxExistsBatch*(maxKeyLength+8)) + `"]`, http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if rec := existsRequest(t, handlers, tt.body); rec.Code != tt.wantStatus {
+				t.Errorf("Expected %d, got %d", tt.wantStatus, rec.Code)
+			}
+		})
+	}
+}
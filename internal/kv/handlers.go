@@ -8,16 +8,36 @@ import (
 	"net/http"
 	"strconv"
 	"strings"
+
+	"github.com/zellyn/trifle/internal/codec"
 )
 
 // Handlers provides HTTP handlers for KV operations
 type Handlers struct {
-	store *Store
+	store         *Store
+	legacyKeys    bool
+	maxValueBytes int64
 }
 
+// DefaultMaxValueBytes is the largest value a PUT may store unless
+// SetMaxValueBytes says otherwise
+const DefaultMaxValueBytes = 32 << 20
+
 // NewHandlers creates a new KV handlers instance
 func NewHandlers(store *Store) *Handlers {
-	return &Handlers{store: store}
+	return &Handlers{store: store, legacyKeys: true, maxValueBytes: DefaultMaxValueBytes}
+}
+
+// SetMaxValueBytes sets the largest value a PUT may store; larger bodies
+// get 413 without being read in full
+func (h *Handlers) SetMaxValueBytes(n int64) {
+	h.maxValueBytes = n
+}
+
+// SetLegacyKeys controls access to the legacy user/{email}/ keys. Turn it
+// off once they have been moved with MigrateLegacyKeys.
+func (h *Handlers) SetLegacyKeys(enabled bool) {
+	h.legacyKeys = enabled
 }
 
 // HandleKV handles GET, PUT, DELETE, HEAD for /kv/{key}
@@ -45,17 +65,14 @@ func (h *Handlers) HandleKV(w http.ResponseWriter, r *http.Request) {
 	case http.MethodHead:
 		h.handleHead(w, r, key)
 	default:
+		// Only reached if the route's method filter is missing
+		w.Header().Set("Allow", "GET, HEAD, PUT, DELETE, OPTIONS")
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 	}
 }
 
 // HandleList handles GET /kvlist/{prefix}
 func (h *Handlers) HandleList(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
 	// Extract prefix from path
 	prefix := strings.TrimPrefix(r.URL.Path, "/kvlist/")
 
@@ -120,13 +137,19 @@ func (h *Handlers) handleGet(w http.ResponseWriter, r *http.Request, key string)
 // handlePut stores a value
 func (h *Handlers) handlePut(w http.ResponseWriter, r *http.Request, key string) {
 	// Read request body (raw bytes)
-	value, err := io.ReadAll(r.Body)
+	value, err := io.ReadAll(http.MaxBytesReader(w, r.Body, h.maxValueBytes))
 	if err != nil {
-		http.Error(w, "Failed to read request body", http.StatusBadRequest)
+		codec.RequestError(w, err, "Failed to read request body")
 		return
 	}
 	defer r.Body.Close()
 
+	// Reject keys that aren't safe to write
+	if err := ValidateKey(key); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	// Special case: file/* keys are idempotent
 	if strings.HasPrefix(key, "file/") {
 		// If key exists, just return success (content-addressed storage)
@@ -218,7 +241,7 @@ func (h *Handlers) checkAuth(r *http.Request, key string) error {
 	}
 
 	// For user/* keys (legacy format), check email matches
-	if strings.HasPrefix(key, "user/") {
+	if h.legacyKeys && strings.HasPrefix(key, "user/") {
 		// Extract email from key: user/{email}/...
 		parts := strings.SplitN(key, "/", 3)
 		if len(parts) < 2 {
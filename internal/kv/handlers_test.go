@@ -4,6 +4,7 @@ import (
 	"context"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 )
 
@@ -202,6 +203,25 @@ func TestCheckAuth_LegacyFormat(t *testing.T) {
 	}
 }
 
+func TestCheckAuth_LegacyKeysDisabled(t *testing.T) {
+	store, err := NewStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	handlers := NewHandlers(store)
+	handlers.SetLegacyKeys(false)
+
+	req := httptest.NewRequest(http.MethodGet, "/kv/user/zellyn@gmail.com/profile", nil)
+	req = req.WithContext(context.WithValue(req.Context(), "user_email", "zellyn@gmail.com"))
+
+	if err := handlers.checkAuth(req, "user/zellyn@gmail.com/profile"); err == nil {
+		t.Errorf("Expected legacy key to be denied once disabled")
+	}
+	if err := handlers.checkAuth(req, "domain/gmail.com/user/zellyn/profile"); err != nil {
+		t.Errorf("Expected domain key to still be allowed, got: %v", err)
+	}
+}
+
 func TestCheckAuth_FileKeys(t *testing.T) {
 	store, err := NewStore(t.TempDir())
 	if err != nil {
@@ -343,3 +363,51 @@ func TestCheckAuth_NotAuthenticated(t *testing.T) {
 		})
 	}
 }
+
+func TestHandleKV_PutTooLarge(t *testing.T) {
+	store, err := NewStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	handlers := NewHandlers(store)
+	handlers.SetMaxValueBytes(16)
+
+	put := func(body string) int {
+		key := "domain/example.com/user/alice/profile"
+		req := httptest.NewRequest(http.MethodPut, "/kv/"+key, strings.NewReader(body))
+		req = req.WithContext(context.WithValue(req.Context(), "user_email", "alice@example.com"))
+		rec := httptest.NewRecorder()
+		handlers.HandleKV(rec, req)
+		return rec.Code
+	}
+
+	if code := put(strings.Repeat("x", 17)); code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected 413 for an oversized value, got %d", code)
+	}
+	if store.Exists("domain/example.com/user/alice/profile") {
+		t.Errorf("Oversized value should not have been stored")
+	}
+	if code := put(strings.Repeat("x", 16)); code != http.StatusOK {
+		t.Errorf("Expected 200 for a value at the limit, got %d", code)
+	}
+}
+
+func TestHandleKV_MethodNotAllowed(t *testing.T) {
+	store, err := NewStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	handlers := NewHandlers(store)
+
+	req := httptest.NewRequest(http.MethodPatch, "/kv/domain/example.com/user/alice/profile", nil)
+	req = req.WithContext(context.WithValue(req.Context(), "user_email", "alice@example.com"))
+	rec := httptest.NewRecorder()
+	handlers.HandleKV(rec, req)
+
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected 405, got %d", rec.Code)
+	}
+	if got := rec.Header().Get("Allow"); got != "GET, HEAD, PUT, DELETE, OPTIONS" {
+		t.Errorf("Unexpected Allow header %q", got)
+	}
+}
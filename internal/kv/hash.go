@@ -0,0 +1,122 @@
+package kv
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// hashCache remembers the SHA-256 of stored values so sync doesn't rehash
+// every file on every request. Put records hashes as it writes; values
+// written before a restart are hashed once, on first use. An entry is only
+// trusted while the file's size and modification time still match.
+type hashCache struct {
+	mu      sync.Mutex
+	entries map[string]hashEntry
+}
+
+type hashEntry struct {
+	size    int64
+	modTime time.Time
+	sum     string
+}
+
+func (c *hashCache) get(key string, info os.FileInfo) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[key]
+	if !ok || e.size != info.Size() || !e.modTime.Equal(info.ModTime()) {
+		return "", false
+	}
+	return e.sum, true
+}
+
+func (c *hashCache) set(key string, info os.FileInfo, sum string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.entries == nil {
+		c.entries = make(map[string]hashEntry)
+	}
+	c.entries[key] = hashEntry{size: info.Size(), modTime: info.ModTime(), sum: sum}
+}
+
+// forget drops key and everything under it
+func (c *hashCache) forget(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for k := range c.entries {
+		if k == key || strings.HasPrefix(k, key+"/") {
+			delete(c.entries, k)
+		}
+	}
+}
+
+// hashValue returns the hex SHA-256 of a value
+func hashValue(value []byte) string {
+	sum := sha256.Sum256(value)
+	return hex.EncodeToString(sum[:])
+}
+
+// Hash returns the hex SHA-256 and size of the value stored at key
+func (s *Store) Hash(key string) (string, int64, error) {
+	path, err := s.keyPath(key)
+	if err != nil {
+		return "", 0, err
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", 0, fmt.Errorf("key not found: %s", key)
+		}
+		return "", 0, fmt.Errorf("failed to stat key: %w", err)
+	}
+	if info.IsDir() {
+		return "", 0, fmt.Errorf("key not found: %s", key)
+	}
+	if sum, ok := s.hashes.get(key, info); ok {
+		return sum, info.Size(), nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to read key: %w", err)
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", 0, fmt.Errorf("failed to read key: %w", err)
+	}
+	sum := hex.EncodeToString(h.Sum(nil))
+
+	s.hashes.set(key, info, sum)
+	return sum, info.Size(), nil
+}
+
+// Stat returns the size of the value stored at key and its hex SHA-256 if
+// already known. Unlike Hash it never reads the value, so sum is empty for
+// values not written or hashed since startup.
+func (s *Store) Stat(key string) (size int64, sum string, err error) {
+	path, err := s.keyPath(key)
+	if err != nil {
+		return 0, "", err
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, "", fmt.Errorf("key not found: %s", key)
+		}
+		return 0, "", fmt.Errorf("failed to stat key: %w", err)
+	}
+	if info.IsDir() {
+		return 0, "", fmt.Errorf("key not found: %s", key)
+	}
+	sum, _ = s.hashes.get(key, info)
+	return info.Size(), sum, nil
+}
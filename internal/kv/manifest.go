@@ -0,0 +1,125 @@
+package kv
+
+import (
+	"log/slog"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/zellyn/trifle/internal/codec"
+)
+
+const (
+	// maxManifestBytes bounds the size of a manifest request body
+	maxManifestBytes = 16 << 20
+	// maxInlineValue is the largest value inlined with include_values=true
+	maxInlineValue = 64 << 10
+)
+
+// ManifestRequest describes the client's local state under Prefix
+type ManifestRequest struct {
+	Prefix  string          `json:"prefix"`
+	Entries []ManifestEntry `json:"entries"`
+}
+
+// ManifestEntry is one key and the hex SHA-256 of its value
+type ManifestEntry struct {
+	Key    string `json:"key"`
+	SHA256 string `json:"sha256"`
+}
+
+// ManifestResponse tells the client how its state differs from the server's
+type ManifestResponse struct {
+	Missing []ServerEntry `json:"missing"` // on the server but not the client
+	Changed []ServerEntry `json:"changed"` // on both, with different values
+	Extra   []string      `json:"extra"`   // on the client but not the server
+}
+
+// ServerEntry describes a value on the server. Value is only set when
+// include_values=true and the value is small.
+type ServerEntry struct {
+	Key    string `json:"key"`
+	SHA256 string `json:"sha256"`
+	Size   int64  `json:"size"`
+	Value  []byte `json:"value,omitempty"`
+}
+
+// HandleManifest handles POST /kvsync/manifest: the client sends the hashes
+// of everything it has under a prefix, and gets back in one round trip what
+// it needs to download, what differs, and what only it has. Requests and
+// responses may be JSON or MessagePack (see package codec).
+func (h *Handlers) HandleManifest(w http.ResponseWriter, r *http.Request) {
+	var req ManifestRequest
+	if err := codec.ReadRequest(w, r, maxManifestBytes, &req); err != nil {
+		codec.RequestError(w, err, "Invalid manifest")
+		return
+	}
+	prefix := strings.TrimSuffix(req.Prefix, "/")
+	if prefix == "" {
+		http.Error(w, "Prefix required", http.StatusBadRequest)
+		return
+	}
+
+	// Check authorization for prefix
+	if err := h.checkAuth(r, prefix); err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
+	client := make(map[string]string, len(req.Entries))
+	for _, e := range req.Entries {
+		if !strings.HasPrefix(e.Key, prefix+"/") {
+			http.Error(w, "Key outside prefix: "+e.Key, http.StatusBadRequest)
+			return
+		}
+		client[e.Key] = strings.ToLower(e.SHA256)
+	}
+
+	keys, err := h.store.List(prefix, 0, true)
+	if err != nil {
+		slog.Error("Failed to list keys", "error", err, "prefix", prefix)
+		http.Error(w, "Failed to list keys", http.StatusInternalServerError)
+		return
+	}
+
+	includeValues := r.URL.Query().Get("include_values") == "true"
+	resp := ManifestResponse{Missing: []ServerEntry{}, Changed: []ServerEntry{}, Extra: []string{}}
+	onServer := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		onServer[key] = true
+		clientSum, onClient := client[key]
+
+		sum, size, err := h.store.Hash(key)
+		if err != nil {
+			// Deleted since it was listed; the client will catch up next time
+			continue
+		}
+		if onClient && clientSum == sum {
+			continue
+		}
+
+		entry := ServerEntry{Key: key, SHA256: sum, Size: size}
+		if includeValues && size <= maxInlineValue {
+			if value, err := h.store.Get(key); err == nil && hashValue(value) == sum {
+				entry.Value = value
+			}
+		}
+		if onClient {
+			resp.Changed = append(resp.Changed, entry)
+		} else {
+			resp.Missing = append(resp.Missing, entry)
+		}
+	}
+	for key := range client {
+		if !onServer[key] {
+			resp.Extra = append(resp.Extra, key)
+		}
+	}
+
+	sort.Slice(resp.Missing, func(i, j int) bool { return resp.Missing[i].Key < resp.Missing[j].Key })
+	sort.Slice(resp.Changed, func(i, j int) bool { return resp.Changed[i].Key < resp.Changed[j].Key })
+	sort.Strings(resp.Extra)
+
+	w.Header().Set("Cache-Control", "no-store")
+	codec.WriteResponse(w, r, http.StatusOK, resp)
+}
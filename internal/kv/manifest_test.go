@@ -0,0 +1,262 @@
+package kv
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zellyn/trifle/internal/codec"
+)
+
+const testPrefix = "domain/example.com/user/alice"
+
+func manifestRequest(t *testing.T, handlers *Handlers, query string, req ManifestRequest) (*httptest.ResponseRecorder, ManifestResponse) {
+	t.Helper()
+	body, err := json.Marshal(req)
+	if err != nil {
+		t.Fatalf("Failed to marshal request: %v", err)
+	}
+	r := httptest.NewRequest(http.MethodPost, "/kvsync/manifest"+query, strings.NewReader(string(body)))
+	r = r.WithContext(context.WithValue(r.Context(), "user_email", "alice@example.com"))
+	rec := httptest.NewRecorder()
+	handlers.HandleManifest(rec, r)
+
+	var resp ManifestResponse
+	if rec.Code == http.StatusOK {
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+	}
+	return rec, resp
+}
+
+func TestHandleManifest_Classification(t *testing.T) {
+	store, err := NewStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	handlers := NewHandlers(store)
+
+	for key, value := range map[string]string{
+		"same":    "unchanged",
+		"changed": "server version",
+		"missing": "only on server",
+	} {
+		if err := store.Put(testPrefix+"/"+key, []byte(value)); err != nil {
+			t.Fatalf("Put failed: %v", err)
+		}
+	}
+
+	rec, resp := manifestRequest(t, handlers, "", ManifestRequest{
+		Prefix: testPrefix,
+		Entries: []ManifestEntry{
+			{Key: testPrefix + "/same", SHA256: hashValue([]byte("unchanged"))},
+			{Key: testPrefix + "/changed", SHA256: hashValue([]byte("client version"))},
+			{Key: testPrefix + "/extra", SHA256: hashValue([]byte("only on client"))},
+		},
+	})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+
+	if len(resp.Missing) != 1 || resp.Missing[0].Key != testPrefix+"/missing" {
+		t.Errorf("Expected missing [%s/missing], got %+v", testPrefix, resp.Missing)
+	} else if resp.Missing[0].SHA256 != hashValue([]byte("only on server")) || resp.Missing[0].Size != 14 {
+		t.Errorf("Expected server hash and size for missing key, got %+v", resp.Missing[0])
+	}
+	if len(resp.Changed) != 1 || resp.Changed[0].Key != testPrefix+"/changed" {
+		t.Errorf("Expected changed [%s/changed], got %+v", testPrefix, resp.Changed)
+	} else if resp.Changed[0].SHA256 != hashValue([]byte("server version")) {
+		t.Errorf("Expected server hash for changed key, got %+v", resp.Changed[0])
+	}
+	if len(resp.Extra) != 1 || resp.Extra[0] != testPrefix+"/extra" {
+		t.Errorf("Expected extra [%s/extra], got %v", testPrefix, resp.Extra)
+	}
+	for _, e := range append(resp.Missing, resp.Changed...) {
+		if e.Value != nil {
+			t.Errorf("Expected no inline values without include_values, got one for %s", e.Key)
+		}
+	}
+}
+
+func TestHandleManifest_IncludeValues(t *testing.T) {
+	store, err := NewStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	handlers := NewHandlers(store)
+
+	large := strings.Repeat("x", maxInlineValue+1)
+	store.Put(testPrefix+"/small", []byte("hello"))
+	store.Put(testPrefix+"/large", []byte(large))
+
+	rec, resp := manifestRequest(t, handlers, "?include_values=true", ManifestRequest{Prefix: testPrefix})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+	if len(resp.Missing) != 2 {
+		t.Fatalf("Expected 2 missing keys, got %+v", resp.Missing)
+	}
+	for _, e := range resp.Missing {
+		switch e.Key {
+		case testPrefix + "/small":
+			if string(e.Value) != "hello" {
+				t.Errorf("Expected small value inlined, got %q", e.Value)
+			}
+		case testPrefix + "/large":
+			if e.Value != nil {
+				t.Errorf("Expected large value not inlined, got %d bytes", len(e.Value))
+			}
+		}
+	}
+}
+
+func TestHandleManifest_Rejected(t *testing.T) {
+	store, err := NewStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	handlers := NewHandlers(store)
+
+	tests := []struct {
+		name       string
+		req        ManifestRequest
+		wantStatus int
+	}{
+		{"other user's prefix", ManifestRequest{Prefix: "domain/example.com/user/bob"}, http.StatusForbidden},
+		{"no prefix", ManifestRequest{}, http.StatusBadRequest},
+		{"key outside prefix", ManifestRequest{
+			Prefix:  testPrefix,
+			Entries: []ManifestEntry{{Key: "domain/example.com/user/bob/x", SHA256: "00"}},
+		}, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec, _ := manifestRequest(t, handlers, "", tt.req)
+			if rec.Code != tt.wantStatus {
+				t.Errorf("Expected %d, got %d: %s", tt.wantStatus, rec.Code, rec.Body)
+			}
+		})
+	}
+}
+
+func TestStore_Hash(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewStore(dir)
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+
+	key := testPrefix + "/file"
+	if err := store.Put(key, []byte("one")); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	sum, size, err := store.Hash(key)
+	if err != nil {
+		t.Fatalf("Hash failed: %v", err)
+	}
+	if sum != hashValue([]byte("one")) || size != 3 {
+		t.Errorf("Expected hash of %q, got %s (%d bytes)", "one", sum, size)
+	}
+
+	// A file changed behind the store's back is rehashed, not served stale
+	path := filepath.Join(dir, key)
+	if err := os.WriteFile(path, []byte("three"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	os.Chtimes(path, time.Now(), time.Now().Add(time.Minute))
+	if sum, _, _ := store.Hash(key); sum != hashValue([]byte("three")) {
+		t.Errorf("Expected rehash after outside change, got %s", sum)
+	}
+
+	if err := store.Delete(key); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if _, _, err := store.Hash(key); err == nil {
+		t.Error("Expected error hashing a deleted key")
+	}
+}
+
+func TestStore_ListSkipsTempFiles(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewStore(dir)
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	store.Put(testPrefix+"/file", []byte("x"))
+	os.WriteFile(filepath.Join(dir, testPrefix, ".put-123"), []byte("partial"), 0644)
+
+	for _, recursive := range []bool{true, false} {
+		keys, err := store.List(testPrefix, 1, recursive)
+		if err != nil {
+			t.Fatalf("List failed: %v", err)
+		}
+		if len(keys) != 1 || keys[0] != testPrefix+"/file" {
+			t.Errorf("Expected only the stored key (recursive=%v), got %v", recursive, keys)
+		}
+	}
+}
+
+func TestHandleManifest_MessagePack(t *testing.T) {
+	store, err := NewStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	handlers := NewHandlers(store)
+
+	value := make([]byte, 4096)
+	for i := range value {
+		value[i] = byte(i)
+	}
+	store.Put(testPrefix+"/blob", value)
+
+	sizes := map[string]int{}
+	for _, c := range []codec.Codec{codec.JSON, codec.MessagePack} {
+		body, err := c.Marshal(ManifestRequest{Prefix: testPrefix})
+		if err != nil {
+			t.Fatalf("Marshal failed: %v", err)
+		}
+		r := httptest.NewRequest(http.MethodPost, "/kvsync/manifest?include_values=true", bytes.NewReader(body))
+		r.Header.Set("Content-Type", c.ContentType())
+		r.Header.Set("Accept", c.ContentType())
+		r = r.WithContext(context.WithValue(r.Context(), "user_email", "alice@example.com"))
+		rec := httptest.NewRecorder()
+		handlers.HandleManifest(rec, r)
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: expected 200, got %d: %s", c.ContentType(), rec.Code, rec.Body)
+		}
+		if got := rec.Header().Get("Content-Type"); got != c.ContentType() {
+			t.Errorf("Expected Content-Type %s, got %s", c.ContentType(), got)
+		}
+		sizes[c.ContentType()] = rec.Body.Len()
+
+		var resp ManifestResponse
+		if err := c.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("%s: failed to decode response: %v", c.ContentType(), err)
+		}
+		if len(resp.Missing) != 1 || !bytes.Equal(resp.Missing[0].Value, value) {
+			t.Errorf("%s: expected the value back byte-exact", c.ContentType())
+		}
+	}
+
+	if sizes["application/msgpack"] >= sizes["application/json"] {
+		t.Errorf("Expected MessagePack to be smaller than JSON, got %v", sizes)
+	}
+
+	r := httptest.NewRequest(http.MethodPost, "/kvsync/manifest", strings.NewReader("x"))
+	r.Header.Set("Content-Type", "application/cbor")
+	rec := httptest.NewRecorder()
+	handlers.HandleManifest(rec, r)
+	if rec.Code != http.StatusUnsupportedMediaType {
+		t.Errorf("Expected 415 for an unsupported encoding, got %d", rec.Code)
+	}
+}
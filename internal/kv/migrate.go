@@ -0,0 +1,151 @@
+package kv
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// legacyPrefix is the root of the old key layout, user/{email}/...
+const legacyPrefix = "user"
+
+// KeyMove is one legacy key and its domain/ layout equivalent
+type KeyMove struct {
+	From string
+	To   string
+}
+
+// FailedMove is a KeyMove that couldn't be completed, and why
+type FailedMove struct {
+	KeyMove
+	Err error
+}
+
+// LegacyMigration reports what MigrateLegacyKeys did, or would do
+type LegacyMigration struct {
+	Moved      []KeyMove    // copied, verified, and the legacy key removed
+	Duplicates []KeyMove    // the new key already held the same value; legacy key removed
+	Conflicts  []KeyMove    // the new key holds a different value; both left alone
+	Invalid    []string     // no parseable email in the key; left alone
+	Failed     []FailedMove // read, copy, or verification failed; legacy key kept
+}
+
+// MigrateLegacyKeys moves every key under user/{email}/ to the domain/
+// layout, domain/{domain}/user/{localpart}/, lowercasing the email as
+// UserPrefix does. Each value is copied, read back and compared, and only
+// then is the legacy key deleted, so an interrupted run can simply be
+// repeated. Keys whose email can't be parsed, and keys whose new location
+// already holds a different value, are reported and left in place.
+//
+// With dryRun, nothing is changed and the report says what would happen.
+func (s *Store) MigrateLegacyKeys(dryRun bool) (*LegacyMigration, error) {
+	keys, err := s.List(legacyPrefix, 0, true)
+	if err != nil {
+		return nil, err
+	}
+	slices.Sort(keys)
+
+	report := &LegacyMigration{}
+	for _, key := range keys {
+		to, ok := legacyKeyTarget(key)
+		if !ok {
+			report.Invalid = append(report.Invalid, key)
+			continue
+		}
+		move := KeyMove{From: key, To: to}
+
+		value, err := s.Get(key)
+		if err != nil {
+			report.Failed = append(report.Failed, FailedMove{move, fmt.Errorf("failed to read legacy key: %w", err)})
+			continue
+		}
+
+		if existing, err := s.Get(to); err == nil {
+			if !bytes.Equal(existing, value) {
+				report.Conflicts = append(report.Conflicts, move)
+				continue
+			}
+			if !dryRun {
+				if err := s.Delete(key); err != nil {
+					report.Failed = append(report.Failed, FailedMove{move, fmt.Errorf("failed to delete legacy key: %w", err)})
+					continue
+				}
+			}
+			report.Duplicates = append(report.Duplicates, move)
+			continue
+		}
+
+		if !dryRun {
+			if err := s.copyVerified(key, to, value); err != nil {
+				report.Failed = append(report.Failed, FailedMove{move, err})
+				continue
+			}
+		}
+		report.Moved = append(report.Moved, move)
+	}
+
+	if !dryRun {
+		s.removeEmptyDirs(legacyPrefix)
+	}
+	return report, nil
+}
+
+// copyVerified writes value to "to", checks it reads back identically, and
+// then deletes "from"
+func (s *Store) copyVerified(from, to string, value []byte) error {
+	if err := s.Put(to, value); err != nil {
+		return fmt.Errorf("failed to write new key: %w", err)
+	}
+	written, err := s.Get(to)
+	if err != nil {
+		return fmt.Errorf("failed to read back new key: %w", err)
+	}
+	if !bytes.Equal(written, value) {
+		return fmt.Errorf("verification failed for %s", to)
+	}
+	if err := s.Delete(from); err != nil {
+		return fmt.Errorf("failed to delete legacy key: %w", err)
+	}
+	return nil
+}
+
+// legacyKeyTarget maps "user/Alice@Example.com/trifle/x" to
+// "domain/example.com/user/alice/trifle/x"
+func legacyKeyTarget(key string) (string, bool) {
+	parts := strings.SplitN(key, "/", 3)
+	if len(parts) < 3 || parts[0] != legacyPrefix || parts[2] == "" {
+		return "", false
+	}
+	prefix, err := UserPrefix(parts[1])
+	if err != nil {
+		return "", false
+	}
+	to := prefix + "/" + parts[2]
+	if ValidateKey(to) != nil {
+		return "", false
+	}
+	return to, true
+}
+
+// removeEmptyDirs removes directories under prefix left empty by deletes,
+// deepest first. Directories that still hold keys are kept.
+func (s *Store) removeEmptyDirs(prefix string) {
+	root, err := s.keyPath(prefix)
+	if err != nil {
+		return
+	}
+	var dirs []string
+	filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err == nil && d.IsDir() {
+			dirs = append(dirs, path)
+		}
+		return nil
+	})
+	for _, dir := range slices.Backward(dirs) {
+		os.Remove(dir) // fails, harmlessly, unless empty
+	}
+}
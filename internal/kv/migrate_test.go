@@ -0,0 +1,149 @@
+package kv
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestMigrateLegacyKeys(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewStore(dir)
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+
+	legacy := map[string]string{
+		"user/Alice@Example.COM/profile":           "alice",
+		"user/bob+trifles@example.com/trifle/t1":   "bob",
+		"user/carol@example.com/profile":           "carol-old",
+		"user/dave@example.com/profile":            "dave",
+		"user/not-an-email/profile":                "nobody",
+		"file/ab/cd/abcd":                          "untouched",
+		"domain/example.com/user/erin/profile":     "erin",
+		"user/frank@example.com/trifle/t2/version": "frank",
+	}
+	for key, value := range legacy {
+		if err := store.Put(key, []byte(value)); err != nil {
+			t.Fatalf("Put %s failed: %v", key, err)
+		}
+	}
+	// carol's new key already holds something else; dave's holds the same value
+	if err := store.Put("domain/example.com/user/carol/profile", []byte("carol-new")); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Put("domain/example.com/user/dave/profile", []byte("dave")); err != nil {
+		t.Fatal(err)
+	}
+
+	// A dry run reports the plan and changes nothing
+	plan, err := store.MigrateLegacyKeys(true)
+	if err != nil {
+		t.Fatalf("Dry run failed: %v", err)
+	}
+	if len(plan.Moved) != 3 {
+		t.Errorf("Expected 3 planned moves, got %v", plan.Moved)
+	}
+	if !store.Exists("user/Alice@Example.COM/profile") || store.Exists("domain/example.com/user/alice/profile") {
+		t.Errorf("Dry run should not change anything")
+	}
+
+	report, err := store.MigrateLegacyKeys(false)
+	if err != nil {
+		t.Fatalf("Migration failed: %v", err)
+	}
+
+	wantMoved := []KeyMove{
+		{From: "user/Alice@Example.COM/profile", To: "domain/example.com/user/alice/profile"},
+		{From: "user/bob+trifles@example.com/trifle/t1", To: "domain/example.com/user/bob+trifles/trifle/t1"},
+		{From: "user/frank@example.com/trifle/t2/version", To: "domain/example.com/user/frank/trifle/t2/version"},
+	}
+	if !slices.Equal(report.Moved, wantMoved) {
+		t.Errorf("Moved = %v, want %v", report.Moved, wantMoved)
+	}
+	if !slices.Equal(plan.Moved, report.Moved) {
+		t.Errorf("Dry run planned %v, but moved %v", plan.Moved, report.Moved)
+	}
+	for _, move := range wantMoved {
+		value, err := store.Get(move.To)
+		if err != nil || string(value) != legacy[move.From] {
+			t.Errorf("Get(%s) = %q, %v; want %q", move.To, value, err, legacy[move.From])
+		}
+		if store.Exists(move.From) {
+			t.Errorf("Expected %s to be removed", move.From)
+		}
+	}
+
+	wantDuplicates := []KeyMove{{From: "user/dave@example.com/profile", To: "domain/example.com/user/dave/profile"}}
+	if !slices.Equal(report.Duplicates, wantDuplicates) {
+		t.Errorf("Duplicates = %v, want %v", report.Duplicates, wantDuplicates)
+	}
+	if store.Exists("user/dave@example.com/profile") {
+		t.Errorf("Expected duplicate legacy key to be removed")
+	}
+
+	wantConflicts := []KeyMove{{From: "user/carol@example.com/profile", To: "domain/example.com/user/carol/profile"}}
+	if !slices.Equal(report.Conflicts, wantConflicts) {
+		t.Errorf("Conflicts = %v, want %v", report.Conflicts, wantConflicts)
+	}
+	if value, _ := store.Get("user/carol@example.com/profile"); string(value) != "carol-old" {
+		t.Errorf("Expected conflicting legacy key to be kept, got %q", value)
+	}
+	if value, _ := store.Get("domain/example.com/user/carol/profile"); string(value) != "carol-new" {
+		t.Errorf("Expected conflicting new key to be kept, got %q", value)
+	}
+
+	if !slices.Equal(report.Invalid, []string{"user/not-an-email/profile"}) {
+		t.Errorf("Invalid = %v", report.Invalid)
+	}
+	if !store.Exists("user/not-an-email/profile") {
+		t.Errorf("Expected unparseable key to be kept")
+	}
+	if len(report.Failed) != 0 {
+		t.Errorf("Failed = %v", report.Failed)
+	}
+
+	// Emptied legacy directories are cleaned up
+	if _, err := os.Stat(filepath.Join(dir, "user", "frank@example.com")); !os.IsNotExist(err) {
+		t.Errorf("Expected empty legacy directory to be removed, got: %v", err)
+	}
+	if value, _ := store.Get("file/ab/cd/abcd"); string(value) != "untouched" {
+		t.Errorf("Expected keys outside user/ to be untouched")
+	}
+
+	// A second run only finds what was left behind
+	again, err := store.MigrateLegacyKeys(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(again.Moved) != 0 || len(again.Duplicates) != 0 || len(again.Conflicts) != 1 || len(again.Invalid) != 1 {
+		t.Errorf("Unexpected second run: %+v", again)
+	}
+}
+
+func TestMigrateLegacyKeys_ReportsFailures(t *testing.T) {
+	store, err := NewStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	// The new key's parent directory is already a value, so the copy fails
+	store.Put("user/alice@example.com/trifle/t1", []byte("t1"))
+	store.Put("domain/example.com/user/alice/trifle", []byte("in the way"))
+
+	report, err := store.MigrateLegacyKeys(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Failed) != 1 {
+		t.Fatalf("Expected one failure, got %+v", report)
+	}
+	failed := report.Failed[0]
+	if failed.From != "user/alice@example.com/trifle/t1" || failed.Err == nil || !strings.Contains(failed.Err.Error(), "failed to write new key") {
+		t.Errorf("Expected the failed write and its reason, got %+v", failed)
+	}
+	if !store.Exists("user/alice@example.com/trifle/t1") {
+		t.Errorf("Expected the legacy key to be kept after a failure")
+	}
+}
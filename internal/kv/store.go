@@ -7,11 +7,28 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"unicode"
+	"unicode/utf8"
 )
 
+const (
+	// maxKeyLength is the maximum length of a key in bytes
+	maxKeyLength = 1024
+	// maxKeyDepth is the maximum number of slash-separated segments in a key
+	maxKeyDepth = 32
+)
+
+// Observer is notified of every store operation, e.g. to record metrics.
+// bytes is the size of the value read or written (0 for other operations).
+type Observer interface {
+	ObserveOp(op string, bytes int, err error)
+}
+
 // Store manages key-value storage operations
 type Store struct {
-	dataDir string
+	dataDir  string
+	observer Observer
+	hashes   hashCache
 }
 
 // NewStore creates a new KV store instance
@@ -26,6 +43,41 @@ func NewStore(dataDir string) (*Store, error) {
 	}, nil
 }
 
+// SetObserver registers an observer for store operations.
+// It must be called before the store is used concurrently.
+func (s *Store) SetObserver(o Observer) {
+	s.observer = o
+}
+
+// observe reports an operation to the observer, if any
+func (s *Store) observe(op string, bytes int, err error) {
+	if s.observer != nil {
+		s.observer.ObserveOp(op, bytes, err)
+	}
+}
+
+// Close releases resources held by the store. Nothing is held today; it
+// exists so background work (sweepers, watchers, caches) has a shutdown hook.
+func (s *Store) Close() error {
+	return nil
+}
+
+// CheckWritable verifies the data directory accepts writes by creating and
+// removing a probe file
+func (s *Store) CheckWritable() error {
+	probe, err := os.CreateTemp(s.dataDir, ".probe-*")
+	if err != nil {
+		return fmt.Errorf("data directory not writable: %w", err)
+	}
+	name := probe.Name()
+	probe.Close()
+
+	if err := os.Remove(name); err != nil {
+		return fmt.Errorf("failed to remove probe file: %w", err)
+	}
+	return nil
+}
+
 // keyPath converts a key to a filesystem path
 // key "user/alice@example.com/profile" -> "data/user/alice@example.com/profile"
 func (s *Store) keyPath(key string) (string, error) {
@@ -40,8 +92,75 @@ func (s *Store) keyPath(key string) (string, error) {
 	return filepath.Join(s.dataDir, key), nil
 }
 
+// KeyError describes why a key was rejected by ValidateKey
+type KeyError struct {
+	Key    string
+	Reason string
+}
+
+func (e *KeyError) Error() string {
+	return fmt.Sprintf("invalid key %q: %s", e.Key, e.Reason)
+}
+
+// ValidateKey checks that a key is safe and canonical for writing.
+//
+// Keys are case-sensitive and use forward slashes as the only separator.
+// Every segment must be non-empty and must not be "." or "..", so that two
+// different keys can never map to the same file on disk. Backslashes and
+// control characters are rejected outright.
+//
+// Only writes are validated this strictly: keys stored before these rules
+// existed can still be read, listed, and deleted.
+func ValidateKey(key string) error {
+	if key == "" {
+		return &KeyError{Key: key, Reason: "empty key"}
+	}
+	if len(key) > maxKeyLength {
+		return &KeyError{Key: key, Reason: fmt.Sprintf("longer than %d bytes", maxKeyLength)}
+	}
+	if strings.HasPrefix(key, "/") {
+		return &KeyError{Key: key, Reason: "starts with '/'"}
+	}
+	if !utf8.ValidString(key) {
+		return &KeyError{Key: key, Reason: "not valid UTF-8"}
+	}
+	for _, r := range key {
+		if r == '\\' {
+			return &KeyError{Key: key, Reason: "contains a backslash"}
+		}
+		if unicode.IsControl(r) {
+			return &KeyError{Key: key, Reason: "contains a control character"}
+		}
+	}
+
+	segments := strings.Split(key, "/")
+	if len(segments) > maxKeyDepth {
+		return &KeyError{Key: key, Reason: fmt.Sprintf("more than %d segments", maxKeyDepth)}
+	}
+	for _, segment := range segments {
+		switch segment {
+		case "":
+			return &KeyError{Key: key, Reason: "contains an empty segment"}
+		case ".", "..":
+			return &KeyError{Key: key, Reason: fmt.Sprintf("contains a %q segment", segment)}
+		}
+		// List skips these as Puts in progress, so the key could never be listed
+		if isTempFile(segment) {
+			return &KeyError{Key: key, Reason: "has a segment starting with \".put-\""}
+		}
+	}
+
+	return nil
+}
+
 // Get retrieves a value by key
 func (s *Store) Get(key string) ([]byte, error) {
+	value, err := s.get(key)
+	s.observe("get", len(value), err)
+	return value, err
+}
+
+func (s *Store) get(key string) ([]byte, error) {
 	path, err := s.keyPath(key)
 	if err != nil {
 		return nil, err
@@ -60,6 +179,16 @@ func (s *Store) Get(key string) ([]byte, error) {
 
 // Put stores a value by key (upsert)
 func (s *Store) Put(key string, value []byte) error {
+	err := s.put(key, value)
+	s.observe("put", len(value), err)
+	return err
+}
+
+func (s *Store) put(key string, value []byte) error {
+	if err := ValidateKey(key); err != nil {
+		return err
+	}
+
 	path, err := s.keyPath(key)
 	if err != nil {
 		return err
@@ -70,20 +199,51 @@ func (s *Store) Put(key string, value []byte) error {
 		return fmt.Errorf("failed to create directories: %w", err)
 	}
 
-	// Write value
-	if err := os.WriteFile(path, value, 0644); err != nil {
+	// Write to a temporary file and rename it into place, so readers never
+	// see a partial value and the hash recorded below belongs to exactly
+	// this file even if another write to the key races with this one
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".put-*")
+	if err != nil {
+		return fmt.Errorf("failed to write key: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.Write(value)
+	if err == nil {
+		err = tmp.Chmod(0644)
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write key: %w", err)
+	}
+	info, err := os.Stat(tmp.Name())
+	if err != nil {
+		return fmt.Errorf("failed to write key: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
 		return fmt.Errorf("failed to write key: %w", err)
 	}
 
+	// Record the hash for sync while we have the value in hand
+	s.hashes.set(key, info, hashValue(value))
+
 	return nil
 }
 
 // Delete removes a key and all its descendants (if it's a prefix)
 func (s *Store) Delete(key string) error {
+	err := s.delete(key)
+	s.observe("delete", 0, err)
+	return err
+}
+
+func (s *Store) delete(key string) error {
 	path, err := s.keyPath(key)
 	if err != nil {
 		return err
 	}
+	defer s.hashes.forget(key)
 
 	// Check if path exists
 	info, err := os.Stat(path)
@@ -109,6 +269,59 @@ func (s *Store) Delete(key string) error {
 	return nil
 }
 
+// Move renames a key, or a prefix and all its descendants, to a new
+// location. It fails if the destination already exists.
+func (s *Store) Move(from, to string) error {
+	err := s.move(from, to)
+	s.observe("move", 0, err)
+	return err
+}
+
+func (s *Store) move(from, to string) error {
+	if err := ValidateKey(to); err != nil {
+		return err
+	}
+
+	fromPath, err := s.keyPath(from)
+	if err != nil {
+		return err
+	}
+	toPath, err := s.keyPath(to)
+	if err != nil {
+		return err
+	}
+
+	if _, err := os.Stat(fromPath); err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("key not found: %s", from)
+		}
+		return fmt.Errorf("failed to stat key: %w", err)
+	}
+	if _, err := os.Stat(toPath); err == nil {
+		return fmt.Errorf("destination already exists: %s", to)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(toPath), 0755); err != nil {
+		return fmt.Errorf("failed to create directories: %w", err)
+	}
+	if err := os.Rename(fromPath, toPath); err != nil {
+		return fmt.Errorf("failed to move key: %w", err)
+	}
+	s.hashes.forget(from)
+	return nil
+}
+
+// UserPrefix returns the key prefix holding a user's data:
+// "Alice@Example.com" -> "domain/example.com/user/alice"
+func UserPrefix(email string) (string, error) {
+	email = strings.ToLower(strings.TrimSpace(email))
+	at := strings.LastIndex(email, "@")
+	if at <= 0 || at == len(email)-1 {
+		return "", fmt.Errorf("invalid email format: %q", email)
+	}
+	return "domain/" + email[at+1:] + "/user/" + email[:at], nil
+}
+
 // Exists checks if a key exists
 func (s *Store) Exists(key string) bool {
 	path, err := s.keyPath(key)
@@ -122,6 +335,12 @@ func (s *Store) Exists(key string) bool {
 
 // List returns keys matching a prefix
 func (s *Store) List(prefix string, depth int, recursive bool) ([]string, error) {
+	keys, err := s.list(prefix, depth, recursive)
+	s.observe("list", 0, err)
+	return keys, err
+}
+
+func (s *Store) list(prefix string, depth int, recursive bool) ([]string, error) {
 	prefixPath, err := s.keyPath(prefix)
 	if err != nil {
 		return nil, err
@@ -142,8 +361,8 @@ func (s *Store) List(prefix string, depth int, recursive bool) ([]string, error)
 				return err
 			}
 
-			// Skip directories, only return files (actual keys)
-			if info.IsDir() {
+			// Skip directories and in-progress writes, only return files (actual keys)
+			if info.IsDir() || isTempFile(info.Name()) {
 				return nil
 			}
 
@@ -159,8 +378,8 @@ func (s *Store) List(prefix string, depth int, recursive bool) ([]string, error)
 	} else {
 		// Walk with depth limit
 		err = s.walkWithDepth(prefixPath, 0, depth, func(path string, info os.FileInfo) error {
-			// Skip directories, only return files
-			if info.IsDir() {
+			// Skip directories and in-progress writes, only return files
+			if info.IsDir() || isTempFile(info.Name()) {
 				return nil
 			}
 
@@ -182,6 +401,11 @@ func (s *Store) List(prefix string, depth int, recursive bool) ([]string, error)
 	return keys, nil
 }
 
+// isTempFile reports whether name is a Put in progress (or left behind by a crash)
+func isTempFile(name string) bool {
+	return strings.HasPrefix(name, ".put-")
+}
+
 // walkWithDepth walks a directory tree up to a specified depth
 func (s *Store) walkWithDepth(root string, currentDepth, maxDepth int, fn func(string, os.FileInfo) error) error {
 	entries, err := os.ReadDir(root)
@@ -0,0 +1,200 @@
+package kv
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestValidateKey(t *testing.T) {
+	tests := []struct {
+		name    string
+		key     string
+		wantErr bool
+	}{
+		{name: "profile key", key: "domain/example.com/user/alice/profile"},
+		{name: "plus addressing", key: "domain/example.com/user/alice+tag/profile"},
+		{name: "legacy user key", key: "user/alice@example.com/profile"},
+		{name: "content-addressed file", key: "file/ab/cd/abcd1234"},
+		{name: "dots inside segment", key: "domain/mail.example.com/user/a.b/profile"},
+		{name: "dotfile", key: "domain/example.com/user/alice/.profile"},
+		{name: "unicode segment", key: "domain/example.com/user/zoë/profile"},
+		{name: "single segment", key: "profile"},
+		{name: "max depth", key: strings.Repeat("a/", maxKeyDepth-1) + "a"},
+		{name: "max length", key: strings.Repeat("a", maxKeyLength)},
+
+		{name: "empty", key: "", wantErr: true},
+		{name: "leading slash", key: "/file/ab", wantErr: true},
+		{name: "trailing slash", key: "file/ab/", wantErr: true},
+		{name: "double slash", key: "file//ab", wantErr: true},
+		{name: "lone dot", key: ".", wantErr: true},
+		{name: "leading dot segment", key: "./file/ab", wantErr: true},
+		{name: "inner dot segment", key: "file/./ab", wantErr: true},
+		{name: "dot-dot segment", key: "file/../ab", wantErr: true},
+		{name: "temp file name", key: "domain/example.com/user/alice/.put-123", wantErr: true},
+		{name: "temp file directory", key: "file/.put-ab/cd", wantErr: true},
+		{name: "backslash", key: `file\ab`, wantErr: true},
+		{name: "newline", key: "file/a\nb", wantErr: true},
+		{name: "nul byte", key: "file/a\x00b", wantErr: true},
+		{name: "delete char", key: "file/a\x7fb", wantErr: true},
+		{name: "invalid utf-8", key: "file/a\xffb", wantErr: true},
+		{name: "too deep", key: strings.Repeat("a/", maxKeyDepth) + "a", wantErr: true},
+		{name: "too long", key: strings.Repeat("a", maxKeyLength+1), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateKey(tt.key)
+			if tt.wantErr {
+				var keyErr *KeyError
+				if !errors.As(err, &keyErr) {
+					t.Fatalf("Expected *KeyError but got: %v", err)
+				}
+				if keyErr.Reason == "" {
+					t.Errorf("Expected a reason in KeyError")
+				}
+			} else if err != nil {
+				t.Errorf("Expected key to be valid but got: %v", err)
+			}
+		})
+	}
+}
+
+func TestStore_PutRejectsInvalidKeys(t *testing.T) {
+	store, err := NewStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+
+	if err := store.Put("file//ab", []byte("x")); err == nil {
+		t.Errorf("Expected Put to reject key with empty segment")
+	}
+	if store.Exists("file/ab") {
+		t.Errorf("Rejected Put should not have written anything")
+	}
+}
+
+func TestStore_LegacyKeysReadable(t *testing.T) {
+	store, err := NewStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+
+	if err := store.Put("file/ab", []byte("hello")); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+
+	// A non-canonical key that existed before validation was tightened
+	// still resolves for reads and deletes.
+	value, err := store.Get("file//ab")
+	if err != nil {
+		t.Fatalf("Expected grandfathered key to be readable, got: %v", err)
+	}
+	if string(value) != "hello" {
+		t.Errorf("Expected %q, got %q", "hello", value)
+	}
+	if err := store.Delete("file//ab"); err != nil {
+		t.Errorf("Expected grandfathered key to be deletable, got: %v", err)
+	}
+}
+
+func TestStore_CheckWritable(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewStore(dir)
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+
+	if err := store.CheckWritable(); err != nil {
+		t.Errorf("Expected fresh data dir to be writable, got: %v", err)
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 0 {
+		t.Errorf("Expected probe file to be cleaned up, found %d entries", len(entries))
+	}
+
+	// A vanished data directory (e.g. an unmounted volume) is not ready
+	if err := os.RemoveAll(dir); err != nil {
+		t.Fatalf("Failed to remove data dir: %v", err)
+	}
+	if err := store.CheckWritable(); err == nil {
+		t.Errorf("Expected missing data dir to fail the writable check")
+	}
+}
+
+func TestStore_CheckWritable_ReadOnly(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("root ignores directory permissions")
+	}
+
+	dir := t.TempDir()
+	store, err := NewStore(dir)
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	if err := os.Chmod(dir, 0555); err != nil {
+		t.Fatalf("Failed to make data dir read-only: %v", err)
+	}
+	defer os.Chmod(dir, 0755)
+
+	if err := store.CheckWritable(); err == nil {
+		t.Errorf("Expected read-only data dir to fail the writable check")
+	}
+}
+
+func TestStore_Move(t *testing.T) {
+	store, err := NewStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	for _, key := range []string{"a/b/one", "a/b/two", "c/taken"} {
+		if err := store.Put(key, []byte(key)); err != nil {
+			t.Fatalf("Put failed: %v", err)
+		}
+	}
+
+	if err := store.Move("a/b", "x/y"); err != nil {
+		t.Fatalf("Move failed: %v", err)
+	}
+	if value, err := store.Get("x/y/two"); err != nil || string(value) != "a/b/two" {
+		t.Errorf("Expected moved value, got %q, %v", value, err)
+	}
+	if store.Exists("a/b") {
+		t.Errorf("Expected source prefix to be gone")
+	}
+
+	if err := store.Move("x/y/one", "c/taken"); err == nil {
+		t.Errorf("Expected moving onto an existing key to fail")
+	}
+	if err := store.Move("missing", "d"); err == nil {
+		t.Errorf("Expected moving a missing key to fail")
+	}
+	if err := store.Move("x/y", "../escape"); err == nil {
+		t.Errorf("Expected an invalid destination to be rejected")
+	}
+}
+
+func TestUserPrefix(t *testing.T) {
+	tests := []struct {
+		email   string
+		want    string
+		wantErr bool
+	}{
+		{email: "alice@example.com", want: "domain/example.com/user/alice"},
+		{email: " Alice+Tag@Example.COM ", want: "domain/example.com/user/alice+tag"},
+		{email: "alice", wantErr: true},
+		{email: "@example.com", wantErr: true},
+		{email: "alice@", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := UserPrefix(tt.email)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("UserPrefix(%q) error = %v, wantErr %v", tt.email, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("UserPrefix(%q) = %q, want %q", tt.email, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package kv
+
+import (
+	"encoding/json"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Page sizes for HandleUsers
+const (
+	defaultUsersLimit = 100
+	maxUsersLimit     = 1000
+)
+
+// UserUsage summarizes one user's synced data
+type UserUsage struct {
+	Email        string     `json:"email"`
+	Keys         int        `json:"keys"`
+	Bytes        int64      `json:"bytes"`
+	LastModified time.Time  `json:"last_modified"`       // latest write to any of the user's keys
+	LastSeen     *time.Time `json:"last_seen,omitempty"` // latest request in a live session
+}
+
+// UsersResponse is one page of HandleUsers
+type UsersResponse struct {
+	Total int         `json:"total"` // users matching the search, across all pages
+	Users []UserUsage `json:"users"`
+}
+
+// Users returns usage for every user with data under
+// domain/{domain}/user/{localpart}/, sorted by email. Keys still in the
+// legacy user/{email}/ layout aren't counted.
+func (s *Store) Users() ([]UserUsage, error) {
+	domainsDir := filepath.Join(s.dataDir, "domain")
+	domains, err := os.ReadDir(domainsDir)
+	if os.IsNotExist(err) {
+		return []UserUsage{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	users := []UserUsage{}
+	for _, domain := range domains {
+		if !domain.IsDir() {
+			continue
+		}
+		usersDir := filepath.Join(domainsDir, domain.Name(), "user")
+		locals, err := os.ReadDir(usersDir)
+		if err != nil {
+			continue // a domain/ entry with no users
+		}
+		for _, local := range locals {
+			if !local.IsDir() {
+				continue
+			}
+			usage := UserUsage{Email: local.Name() + "@" + domain.Name()}
+			err := filepath.WalkDir(filepath.Join(usersDir, local.Name()), func(path string, d fs.DirEntry, err error) error {
+				if err != nil {
+					return err
+				}
+				if d.IsDir() || isTempFile(d.Name()) {
+					return nil
+				}
+				info, err := d.Info()
+				if err != nil {
+					return nil // deleted while walking
+				}
+				usage.Keys++
+				usage.Bytes += info.Size()
+				if info.ModTime().After(usage.LastModified) {
+					usage.LastModified = info.ModTime()
+				}
+				return nil
+			})
+			if err != nil {
+				return nil, err
+			}
+			if usage.Keys > 0 {
+				users = append(users, usage)
+			}
+		}
+	}
+	slices.SortFunc(users, func(a, b UserUsage) int { return strings.Compare(a.Email, b.Email) })
+	return users, nil
+}
+
+// HandleUsers serves GET /admin/users: a page of users and their usage, for
+// admins. ?q= filters by email substring; ?limit= and ?offset= page through
+// the results. lastSeen, if non-nil, supplies session activity by email.
+// It must be admin-gated.
+//
+// Every request walks every user's data (see Store.Users), so paging limits
+// the response size but not the cost; fine for an admin page on a
+// classroom-sized server.
+func HandleUsers(store *Store, lastSeen func() map[string]time.Time) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		limit, offset := defaultUsersLimit, 0
+		if v := query.Get("limit"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 1 || n > maxUsersLimit {
+				http.Error(w, "Invalid limit parameter", http.StatusBadRequest)
+				return
+			}
+			limit = n
+		}
+		if v := query.Get("offset"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 {
+				http.Error(w, "Invalid offset parameter", http.StatusBadRequest)
+				return
+			}
+			offset = n
+		}
+
+		users, err := store.Users()
+		if err != nil {
+			slog.Error("Failed to list users", "error", err)
+			http.Error(w, "Failed to list users", http.StatusInternalServerError)
+			return
+		}
+		if q := strings.ToLower(query.Get("q")); q != "" {
+			users = slices.DeleteFunc(users, func(u UserUsage) bool { return !strings.Contains(u.Email, q) })
+		}
+
+		start := min(offset, len(users))
+		end := start + min(limit, len(users)-start)
+		resp := UsersResponse{Total: len(users), Users: users[start:end]}
+		if lastSeen != nil {
+			seen := lastSeen()
+			for i := range resp.Users {
+				if t, ok := seen[resp.Users[i].Email]; ok {
+					resp.Users[i].LastSeen = &t
+				}
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		json.NewEncoder(w).Encode(resp)
+	}
+}
@@ -0,0 +1,74 @@
+package kv
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestHandleUsers(t *testing.T) {
+	store, err := NewStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	store.Put("domain/example.com/user/alice/profile", []byte("hello"))
+	store.Put("domain/example.com/user/alice/trifle/latest/t1/v1", []byte(""))
+	store.Put("domain/school.edu/user/bob/profile", []byte("hi"))
+	store.Put("domain/school.edu/user/carol/profile", []byte("hey"))
+	store.Put("file/ab/cd/abcd", []byte("not a user"))
+
+	seen := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	handler := HandleUsers(store, func() map[string]time.Time {
+		return map[string]time.Time{"bob@school.edu": seen}
+	})
+
+	get := func(query string) UsersResponse {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		handler(rec, httptest.NewRequest(http.MethodGet, "/admin/users"+query, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("GET %s: expected 200, got %d: %s", query, rec.Code, rec.Body)
+		}
+		var resp UsersResponse
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		return resp
+	}
+
+	all := get("")
+	if all.Total != 3 || len(all.Users) != 3 {
+		t.Fatalf("Expected 3 users, got %+v", all)
+	}
+	alice := all.Users[0]
+	if alice.Email != "alice@example.com" || alice.Keys != 2 || alice.Bytes != 5 || alice.LastModified.IsZero() || alice.LastSeen != nil {
+		t.Errorf("Unexpected usage for alice: %+v", alice)
+	}
+	if bob := all.Users[1]; bob.LastSeen == nil || !bob.LastSeen.Equal(seen) {
+		t.Errorf("Expected bob's session activity, got %+v", bob)
+	}
+
+	page := get("?limit=1&offset=1")
+	if page.Total != 3 || len(page.Users) != 1 || page.Users[0].Email != "bob@school.edu" {
+		t.Errorf("Unexpected page: %+v", page)
+	}
+	if past := get("?offset=10"); past.Total != 3 || len(past.Users) != 0 {
+		t.Errorf("Expected an empty page past the end, got %+v", past)
+	}
+	if huge := get("?offset=9223372036854775800&limit=1000"); huge.Total != 3 || len(huge.Users) != 0 {
+		t.Errorf("Expected an empty page for a huge offset, got %+v", huge)
+	}
+	if search := get("?q=SCHOOL"); search.Total != 2 {
+		t.Errorf("Expected 2 users at school.edu, got %+v", search)
+	}
+
+	for _, query := range []string{"?limit=0", "?limit=5000", "?offset=-1", "?limit=x"} {
+		rec := httptest.NewRecorder()
+		handler(rec, httptest.NewRequest(http.MethodGet, "/admin/users"+query, nil))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("GET %s: expected 400, got %d", query, rec.Code)
+		}
+	}
+}
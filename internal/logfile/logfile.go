@@ -0,0 +1,196 @@
+// Package logfile provides an append-only log file that rotates itself by
+// size and age and prunes old rotated files.
+package logfile
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// timestampFormat names rotated files, e.g. access.log.2025-01-31T23-59-59.000
+const timestampFormat = "2006-01-02T15-04-05.000"
+
+// Options controls rotation. Zero values disable the corresponding limit.
+type Options struct {
+	MaxSize int64         // rotate before a write would grow the file past this many bytes
+	MaxAge  time.Duration // rotate once the current file is this old
+	Keep    int           // number of rotated files to keep (0 keeps all)
+}
+
+// File is a rotating log file. It is safe for concurrent use.
+type File struct {
+	path string
+	opts Options
+	now  func() time.Time
+
+	mu       sync.Mutex
+	file     *os.File
+	size     int64
+	openedAt time.Time
+}
+
+// Open opens (or creates) the log file at path for appending
+func Open(path string, opts Options) (*File, error) {
+	return open(path, opts, time.Now)
+}
+
+func open(path string, opts Options, now func() time.Time) (*File, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create log directory: %w", err)
+	}
+
+	f := &File{path: path, opts: opts, now: now}
+
+	// A leftover file that is already too old is rotated right away
+	if info, err := os.Stat(path); err == nil && info.Size() > 0 &&
+		opts.MaxAge > 0 && now().Sub(info.ModTime()) >= opts.MaxAge {
+		if err := f.archive(); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := f.openCurrent(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Write appends p, rotating first if p would exceed MaxSize or the file
+// has reached MaxAge. A single write is never split across files.
+func (f *File) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		return 0, os.ErrClosed
+	}
+
+	if f.size > 0 && f.shouldRotate(len(p)) {
+		if err := f.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+// Close syncs and closes the current file
+func (f *File) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		return nil
+	}
+	syncErr := f.file.Sync()
+	closeErr := f.file.Close()
+	f.file = nil
+	if syncErr != nil {
+		return syncErr
+	}
+	return closeErr
+}
+
+func (f *File) shouldRotate(incoming int) bool {
+	if f.opts.MaxSize > 0 && f.size+int64(incoming) > f.opts.MaxSize {
+		return true
+	}
+	return f.opts.MaxAge > 0 && f.now().Sub(f.openedAt) >= f.opts.MaxAge
+}
+
+// rotate closes the current file, archives it, reopens path, and prunes
+func (f *File) rotate() error {
+	if err := f.file.Close(); err != nil {
+		return fmt.Errorf("failed to close log file: %w", err)
+	}
+	f.file = nil
+
+	archiveErr := f.archive()
+
+	// Keep logging to the current path even if archiving failed
+	if err := f.openCurrent(); err != nil {
+		return err
+	}
+	if archiveErr != nil {
+		return archiveErr
+	}
+	return f.prune()
+}
+
+// archive renames the current file to a timestamped name
+func (f *File) archive() error {
+	name := f.path + "." + f.now().UTC().Format(timestampFormat)
+	for i := 1; ; i++ {
+		if _, err := os.Stat(name); os.IsNotExist(err) {
+			break
+		}
+		name = fmt.Sprintf("%s.%s-%d", f.path, f.now().UTC().Format(timestampFormat), i)
+	}
+	if err := os.Rename(f.path, name); err != nil {
+		return fmt.Errorf("failed to rotate log file: %w", err)
+	}
+	return nil
+}
+
+func (f *File) openCurrent() error {
+	file, err := os.OpenFile(f.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("failed to stat log file: %w", err)
+	}
+	f.file = file
+	f.size = info.Size()
+	f.openedAt = f.now()
+	return nil
+}
+
+// rotatedFiles returns the rotated files for path, oldest first
+func rotatedFiles(path string) ([]string, error) {
+	matches, err := filepath.Glob(path + ".*")
+	if err != nil {
+		return nil, err
+	}
+	prefix := filepath.Base(path) + "."
+	var rotated []string
+	for _, m := range matches {
+		suffix := strings.TrimPrefix(filepath.Base(m), prefix)
+		if len(suffix) < len(timestampFormat) {
+			continue
+		}
+		if _, err := time.Parse(timestampFormat, suffix[:len(timestampFormat)]); err == nil {
+			rotated = append(rotated, m)
+		}
+	}
+	// Timestamps sort lexically
+	sort.Strings(rotated)
+	return rotated, nil
+}
+
+// prune removes the oldest rotated files beyond Keep
+func (f *File) prune() error {
+	if f.opts.Keep <= 0 {
+		return nil
+	}
+	rotated, err := rotatedFiles(f.path)
+	if err != nil {
+		return err
+	}
+	for len(rotated) > f.opts.Keep {
+		if err := os.Remove(rotated[0]); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove old log file: %w", err)
+		}
+		rotated = rotated[1:]
+	}
+	return nil
+}
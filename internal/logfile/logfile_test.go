@@ -0,0 +1,257 @@
+package logfile
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testClock is a controllable time source
+type testClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *testClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *testClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func newClock() *testClock {
+	return &testClock{now: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read %s: %v", path, err)
+	}
+	return string(data)
+}
+
+func write(t *testing.T, f *File, s string) {
+	t.Helper()
+	if _, err := f.Write([]byte(s)); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+}
+
+func TestFile_RotatesAtSizeThreshold(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "access.log")
+	clock := newClock()
+	f, err := open(path, Options{MaxSize: 20}, clock.Now)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer f.Close()
+
+	write(t, f, "0123456789\n") // 11 bytes
+	write(t, f, "abcdefgh\n")   // 20 bytes: exactly at the limit, no rotation
+	rotated, _ := rotatedFiles(path)
+	if len(rotated) != 0 {
+		t.Fatalf("Expected no rotation at the threshold, got %v", rotated)
+	}
+
+	clock.Advance(time.Second)
+	write(t, f, "next\n") // would cross the limit: rotate first
+
+	rotated, _ = rotatedFiles(path)
+	if len(rotated) != 1 {
+		t.Fatalf("Expected one rotated file, got %v", rotated)
+	}
+	if got := readFile(t, rotated[0]); got != "0123456789\nabcdefgh\n" {
+		t.Errorf("Rotated file = %q", got)
+	}
+	if got := readFile(t, path); got != "next\n" {
+		t.Errorf("Current file = %q, want only the post-rotation write", got)
+	}
+	if !strings.HasSuffix(rotated[0], ".2025-01-01T00-00-01.000") {
+		t.Errorf("Unexpected rotated name %s", rotated[0])
+	}
+}
+
+func TestFile_OversizedWriteNotSplit(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "access.log")
+	f, err := open(path, Options{MaxSize: 5}, newClock().Now)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer f.Close()
+
+	write(t, f, "a line longer than the limit\n")
+	if got := readFile(t, path); got != "a line longer than the limit\n" {
+		t.Errorf("Expected an oversized first write to land intact, got %q", got)
+	}
+}
+
+func TestFile_RotatesByAge(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "access.log")
+	clock := newClock()
+	f, err := open(path, Options{MaxAge: time.Hour}, clock.Now)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer f.Close()
+
+	write(t, f, "old\n")
+	clock.Advance(59 * time.Minute)
+	write(t, f, "still old\n")
+	clock.Advance(time.Minute)
+	write(t, f, "new\n")
+
+	rotated, _ := rotatedFiles(path)
+	if len(rotated) != 1 || readFile(t, rotated[0]) != "old\nstill old\n" {
+		t.Fatalf("Unexpected rotation %v", rotated)
+	}
+	if got := readFile(t, path); got != "new\n" {
+		t.Errorf("Current file = %q", got)
+	}
+}
+
+func TestFile_ReopenAppends(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs", "access.log")
+	clock := newClock()
+
+	f, err := open(path, Options{MaxSize: 100}, clock.Now)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	write(t, f, "first\n")
+	if err := f.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if _, err := f.Write([]byte("x")); err == nil {
+		t.Errorf("Expected write after Close to fail")
+	}
+
+	f, err = open(path, Options{MaxSize: 100}, clock.Now)
+	if err != nil {
+		t.Fatalf("Reopen failed: %v", err)
+	}
+	defer f.Close()
+	write(t, f, "second\n")
+	if got := readFile(t, path); got != "first\nsecond\n" {
+		t.Errorf("Expected reopen to append, got %q", got)
+	}
+	if f.size != int64(len("first\nsecond\n")) {
+		t.Errorf("Expected size to include existing contents, got %d", f.size)
+	}
+}
+
+func TestFile_StaleFileRotatedOnOpen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "access.log")
+	if err := os.WriteFile(path, []byte("yesterday\n"), 0644); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+	old := time.Date(2024, 12, 30, 0, 0, 0, 0, time.UTC)
+	os.Chtimes(path, old, old)
+
+	f, err := open(path, Options{MaxAge: 24 * time.Hour}, newClock().Now)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer f.Close()
+
+	rotated, _ := rotatedFiles(path)
+	if len(rotated) != 1 || readFile(t, rotated[0]) != "yesterday\n" {
+		t.Errorf("Expected stale file to be rotated on open, got %v", rotated)
+	}
+}
+
+func TestFile_PrunesOldFiles(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "access.log")
+
+	// Unrelated files next to the log must survive pruning
+	os.WriteFile(filepath.Join(dir, "access.log.bak"), []byte("keep me"), 0644)
+
+	clock := newClock()
+	f, err := open(path, Options{MaxSize: 4, Keep: 2}, clock.Now)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer f.Close()
+
+	for _, line := range []string{"aaa\n", "bbb\n", "ccc\n", "ddd\n", "eee\n"} {
+		write(t, f, line)
+		clock.Advance(time.Second)
+	}
+
+	rotated, _ := rotatedFiles(path)
+	if len(rotated) != 2 {
+		t.Fatalf("Expected 2 rotated files kept, got %v", rotated)
+	}
+	if readFile(t, rotated[0]) != "ccc\n" || readFile(t, rotated[1]) != "ddd\n" {
+		t.Errorf("Expected the newest rotated files to be kept")
+	}
+	if readFile(t, path) != "eee\n" {
+		t.Errorf("Unexpected current file contents")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "access.log.bak")); err != nil {
+		t.Errorf("Pruning removed an unrelated file")
+	}
+}
+
+func TestFile_SameTimestampRotations(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "access.log")
+	f, err := open(path, Options{MaxSize: 4}, newClock().Now)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer f.Close()
+
+	for _, line := range []string{"aaa\n", "bbb\n", "ccc\n"} {
+		write(t, f, line)
+	}
+	rotated, _ := rotatedFiles(path)
+	if len(rotated) != 2 {
+		t.Errorf("Expected rotations in the same millisecond not to overwrite each other, got %v", rotated)
+	}
+}
+
+func TestFile_ConcurrentWrites(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "access.log")
+	f, err := Open(path, Options{MaxSize: 1000})
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				f.Write([]byte("0123456789\n"))
+			}
+		}()
+	}
+	wg.Wait()
+	if err := f.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	rotated, _ := rotatedFiles(path)
+	total := len(readFile(t, path))
+	for _, r := range rotated {
+		contents := readFile(t, r)
+		if len(contents)%11 != 0 {
+			t.Errorf("Rotated file %s has a torn line", r)
+		}
+		total += len(contents)
+	}
+	if total != 8*100*11 {
+		t.Errorf("Expected %d bytes across files, got %d", 8*100*11, total)
+	}
+}
@@ -0,0 +1,113 @@
+// Package maintenance implements a read-only mode for risky operations
+// such as data migrations: reads keep working while writes are refused.
+package maintenance
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/zellyn/trifle/internal/codec"
+)
+
+// RetryAfter is how long clients are told to wait before retrying a write
+const RetryAfter = 2 * time.Minute
+
+// Mode is a concurrency-safe maintenance flag
+type Mode struct {
+	active atomic.Bool
+}
+
+// New creates a maintenance flag with the given initial state
+func New(active bool) *Mode {
+	m := &Mode{}
+	m.active.Store(active)
+	return m
+}
+
+// Active reports whether maintenance mode is on
+func (m *Mode) Active() bool {
+	return m.active.Load()
+}
+
+// Set turns maintenance mode on or off
+func (m *Mode) Set(active bool) {
+	if m.active.Swap(active) != active {
+		slog.Warn("Maintenance mode changed", "active", active)
+	}
+}
+
+// Middleware returns middleware that, while maintenance mode is on, refuses
+// requests under prefixes with 503 unless they are GET, HEAD, or OPTIONS
+func (m *Mode) Middleware(prefixes ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if m.Active() && isWrite(r.Method) && hasPrefix(r.URL.Path, prefixes) {
+				w.Header().Set("Content-Type", "application/json")
+				w.Header().Set("Retry-After", strconv.Itoa(int(RetryAfter.Seconds())))
+				w.Header().Set("Cache-Control", "no-store")
+				w.WriteHeader(http.StatusServiceUnavailable)
+				json.NewEncoder(w).Encode(map[string]string{
+					"error": "The server is in maintenance mode; changes can't be saved right now",
+				})
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// Status is the public server status
+type Status struct {
+	Maintenance bool `json:"maintenance"`
+}
+
+// HandleStatus serves public server status for the frontend to poll
+func (m *Mode) HandleStatus(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(Status{Maintenance: m.Active()})
+}
+
+// HandleToggle reports maintenance mode on GET and changes it on POST
+// with a JSON body like {"maintenance": true}. It must be admin-gated, and
+// its route restricted to GET, HEAD, and POST.
+func (m *Mode) HandleToggle(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodPost {
+		var req struct {
+			Maintenance *bool `json:"maintenance"`
+		}
+		const invalid = `Expected a JSON body like {"maintenance": true}`
+		if err := codec.ReadRequestStrict(w, r, 1024, &req); err != nil {
+			codec.RequestError(w, err, invalid)
+			return
+		}
+		if req.Maintenance == nil {
+			http.Error(w, invalid, http.StatusBadRequest)
+			return
+		}
+		m.Set(*req.Maintenance)
+	}
+	m.HandleStatus(w, r)
+}
+
+func isWrite(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return false
+	}
+	return true
+}
+
+func hasPrefix(path string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
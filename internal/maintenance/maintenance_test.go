@@ -0,0 +1,142 @@
+package maintenance
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/zellyn/trifle/internal/auth"
+	"github.com/zellyn/trifle/internal/middleware"
+)
+
+func TestMiddleware_MethodFiltering(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	tests := []struct {
+		name       string
+		active     bool
+		method     string
+		path       string
+		wantStatus int
+	}{
+		{"inactive put", false, http.MethodPut, "/kv/a", http.StatusOK},
+		{"get kv", true, http.MethodGet, "/kv/a", http.StatusOK},
+		{"head kv", true, http.MethodHead, "/kv/a", http.StatusOK},
+		{"options kv", true, http.MethodOptions, "/kv/a", http.StatusOK},
+		{"put kv", true, http.MethodPut, "/kv/a", http.StatusServiceUnavailable},
+		{"delete kv", true, http.MethodDelete, "/kv/a", http.StatusServiceUnavailable},
+		{"post api", true, http.MethodPost, "/api/thing", http.StatusServiceUnavailable},
+		{"patch api", true, http.MethodPatch, "/api/thing", http.StatusServiceUnavailable},
+		{"post outside prefixes", true, http.MethodPost, "/auth/logout", http.StatusOK},
+		{"post admin", true, http.MethodPost, "/admin/maintenance", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := New(tt.active).Middleware("/api/", "/kv/")(ok)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.path, nil))
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("Expected %d, got %d", tt.wantStatus, rec.Code)
+			}
+			if rec.Code != http.StatusServiceUnavailable {
+				return
+			}
+			if got := rec.Header().Get("Retry-After"); got != "120" {
+				t.Errorf("Expected Retry-After 120, got %q", got)
+			}
+			if got := rec.Header().Get("Content-Type"); got != "application/json" {
+				t.Errorf("Expected JSON, got %q", got)
+			}
+			var body map[string]string
+			if err := json.NewDecoder(rec.Body).Decode(&body); err != nil || body["error"] == "" {
+				t.Errorf("Expected a JSON error body, got %v (%v)", body, err)
+			}
+		})
+	}
+}
+
+func TestMode_ConcurrentToggle(t *testing.T) {
+	m := New(false)
+	handler := m.Middleware("/kv/")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	var wg sync.WaitGroup
+	for i := range 50 {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			m.Set(i%2 == 0)
+		}()
+		go func() {
+			defer wg.Done()
+			handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPut, "/kv/a", nil))
+		}()
+	}
+	wg.Wait()
+}
+
+func TestHandleStatus(t *testing.T) {
+	for _, active := range []bool{false, true} {
+		rec := httptest.NewRecorder()
+		New(active).HandleStatus(rec, httptest.NewRequest(http.MethodGet, "/api/status", nil))
+
+		var body map[string]bool
+		if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		if body["maintenance"] != active {
+			t.Errorf("Expected maintenance %v, got %v", active, body["maintenance"])
+		}
+	}
+}
+
+func TestHandleToggle_AdminGated(t *testing.T) {
+	m := New(false)
+	gate := auth.NewAdminGate(auth.NewSessionManager(false, time.Hour), nil, "secret")
+	handler := middleware.Methods(http.MethodGet, http.MethodHead, http.MethodPost)(gate.Require(http.HandlerFunc(m.HandleToggle)))
+
+	tests := []struct {
+		name       string
+		token      string
+		method     string
+		body       string
+		wantStatus int
+		wantActive bool
+	}{
+		{"anonymous", "", http.MethodPost, `{"maintenance": true}`, http.StatusUnauthorized, false},
+		{"wrong token", "nope", http.MethodPost, `{"maintenance": true}`, http.StatusForbidden, false},
+		{"enable", "secret", http.MethodPost, `{"maintenance": true}`, http.StatusOK, true},
+		{"get", "secret", http.MethodGet, "", http.StatusOK, true},
+		{"missing field", "secret", http.MethodPost, `{}`, http.StatusBadRequest, true},
+		{"unknown field", "secret", http.MethodPost, `{"maintenance": false, "maintenence": false}`, http.StatusBadRequest, true},
+		{"trailing data", "secret", http.MethodPost, `{"maintenance": false} x`, http.StatusBadRequest, true},
+		{"too large", "secret", http.MethodPost, `{"maintenance": false` + strings.Repeat(" ", 2048) + `}`, http.StatusRequestEntityTooLarge, true},
+		{"bad method", "secret", http.MethodDelete, "", http.StatusMethodNotAllowed, true},
+		{"disable", "secret", http.MethodPost, `{"maintenance": false}`, http.StatusOK, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/admin/maintenance", strings.NewReader(tt.body))
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("Expected %d, got %d: %s", tt.wantStatus, rec.Code, rec.Body)
+			}
+			if m.Active() != tt.wantActive {
+				t.Errorf("Expected active %v, got %v", tt.wantActive, m.Active())
+			}
+		})
+	}
+}
@@ -0,0 +1,132 @@
+// Package metrics collects Prometheus metrics for HTTP traffic, the KV
+// store, and the Go runtime, and serves them for scraping.
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/zellyn/trifle/internal/middleware"
+)
+
+const namespace = "trifle"
+
+// Metrics holds the registry and all instruments
+type Metrics struct {
+	registry        *prometheus.Registry
+	requestDuration *prometheus.HistogramVec
+	inFlight        prometheus.Gauge
+	kvOps           *prometheus.CounterVec
+	kvBytes         *prometheus.CounterVec
+}
+
+// New creates a Metrics instance with its own registry, including Go
+// runtime and process collectors
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "HTTP request latency by route, method, and status code.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"route", "method", "status"}),
+		inFlight: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "http_requests_in_flight",
+			Help:      "HTTP requests currently being served.",
+		}),
+		kvOps: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "kv_operations_total",
+			Help:      "KV store operations by operation and result.",
+		}, []string{"op", "result"}),
+		kvBytes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "kv_bytes_total",
+			Help:      "Bytes read from or written to the KV store.",
+		}, []string{"op"}),
+	}
+
+	m.registry.MustRegister(
+		m.requestDuration,
+		m.inFlight,
+		m.kvOps,
+		m.kvBytes,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+
+	return m
+}
+
+// Handler serves the metrics in the Prometheus exposition format
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// GaugeFunc registers a gauge whose value is read from fn at scrape time
+func (m *Metrics) GaugeFunc(name, help string, fn func() float64) {
+	m.registry.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      name,
+		Help:      help,
+	}, fn))
+}
+
+// ObserveOp records a KV store operation (implements kv.Observer)
+func (m *Metrics) ObserveOp(op string, bytes int, err error) {
+	result := "ok"
+	if err != nil {
+		result = "error"
+	}
+	m.kvOps.WithLabelValues(op, result).Inc()
+	if bytes > 0 {
+		m.kvBytes.WithLabelValues(op).Add(float64(bytes))
+	}
+}
+
+// Middleware records request latency and in-flight requests. It must wrap
+// the *http.ServeMux (or middleware that passes the same *http.Request
+// through) so the matched route pattern is available afterwards.
+func (m *Metrics) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		m.inFlight.Inc()
+		defer m.inFlight.Dec()
+
+		start := time.Now()
+		sw := middleware.NewStatusWriter(w)
+		next.ServeHTTP(sw, r)
+
+		m.requestDuration.WithLabelValues(
+			routeLabel(r),
+			methodLabel(r.Method),
+			strconv.Itoa(sw.Status()),
+		).Observe(time.Since(start).Seconds())
+	})
+}
+
+// routeLabel returns the ServeMux pattern that matched the request, so
+// keys and IDs in paths don't become label values
+func routeLabel(r *http.Request) string {
+	if r.Pattern == "" {
+		return "unmatched"
+	}
+	return r.Pattern
+}
+
+// methodLabel collapses non-standard methods to keep label cardinality bounded
+func methodLabel(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut,
+		http.MethodPatch, http.MethodDelete, http.MethodOptions:
+		return method
+	default:
+		return "OTHER"
+	}
+}
@@ -0,0 +1,77 @@
+package metrics
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func scrape(t *testing.T, m *Metrics) string {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Scrape failed with status %d", rec.Code)
+	}
+	body, _ := io.ReadAll(rec.Body)
+	return string(body)
+}
+
+func TestMetrics_Scrape(t *testing.T) {
+	m := New()
+	m.GaugeFunc("sessions", "Sessions held in memory.", func() float64 { return 3 })
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/kv/", func(w http.ResponseWriter, r *http.Request) {
+		http.NotFound(w, r)
+	})
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {})
+	handler := m.Middleware(mux)
+
+	for _, path := range []string{"/kv/domain/a/user/b/profile", "/kv/file/ab/cd/abcd", "/healthz"} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("BREW", "/healthz", nil))
+
+	m.ObserveOp("put", 42, nil)
+	m.ObserveOp("get", 0, errors.New("key not found"))
+
+	out := scrape(t, m)
+
+	for _, want := range []string{
+		"trifle_http_request_duration_seconds_bucket",
+		`trifle_http_request_duration_seconds_count{method="GET",route="/kv/",status="404"} 2`,
+		`trifle_http_request_duration_seconds_count{method="GET",route="/healthz",status="200"} 1`,
+		`trifle_http_request_duration_seconds_count{method="OTHER",route="/healthz",status="200"} 1`,
+		"trifle_http_requests_in_flight 0",
+		`trifle_kv_operations_total{op="put",result="ok"} 1`,
+		`trifle_kv_operations_total{op="get",result="error"} 1`,
+		`trifle_kv_bytes_total{op="put"} 42`,
+		"trifle_sessions 3",
+		"go_goroutines",
+		"go_memstats_alloc_bytes",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected scrape output to contain %q", want)
+		}
+	}
+
+	// Raw paths with keys must never become label values
+	if strings.Contains(out, "domain/a/user/b") {
+		t.Errorf("Raw request path leaked into metric labels")
+	}
+}
+
+func TestMetrics_UnmatchedRoute(t *testing.T) {
+	m := New()
+	handler := m.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/anything/123", nil))
+
+	out := scrape(t, m)
+	if !strings.Contains(out, `route="unmatched"`) {
+		t.Errorf("Expected requests without a mux pattern to be labeled unmatched")
+	}
+}
@@ -0,0 +1,234 @@
+package middleware
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func decompress(t *testing.T, encoding string, body []byte) []byte {
+	t.Helper()
+	var r io.Reader
+	var err error
+	switch encoding {
+	case "gzip":
+		r, err = gzip.NewReader(bytes.NewReader(body))
+	case "deflate":
+		r, err = zlib.NewReader(bytes.NewReader(body))
+	default:
+		return body
+	}
+	if err != nil {
+		t.Fatalf("Failed to open %s reader: %v", encoding, err)
+	}
+	out, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("Failed to decompress %s body: %v", encoding, err)
+	}
+	return out
+}
+
+func TestCompress(t *testing.T) {
+	largeJSON := []byte(`[` + strings.Repeat(`"domain/example.com/user/alice/trifle/latest/x",`, 100) + `"end"]`)
+	smallJSON := []byte(`["a","b"]`)
+	largeZip := append([]byte("PK\x03\x04"), bytes.Repeat([]byte{0}, 4096)...)
+
+	tests := []struct {
+		name           string
+		acceptEncoding string
+		method         string
+		contentType    string
+		body           []byte
+		wantEncoding   string
+	}{
+		{name: "gzip large JSON", acceptEncoding: "gzip, deflate, br", contentType: "application/json", body: largeJSON, wantEncoding: "gzip"},
+		{name: "deflate only", acceptEncoding: "deflate", contentType: "application/json", body: largeJSON, wantEncoding: "deflate"},
+		{name: "gzip refused by q=0", acceptEncoding: "gzip;q=0, deflate", contentType: "application/json", body: largeJSON, wantEncoding: "deflate"},
+		{name: "wildcard", acceptEncoding: "*", contentType: "text/plain; charset=utf-8", body: largeJSON, wantEncoding: "gzip"},
+		{name: "no Accept-Encoding", contentType: "application/json", body: largeJSON},
+		{name: "below threshold", acceptEncoding: "gzip", contentType: "application/json", body: smallJSON},
+		{name: "zip download", acceptEncoding: "gzip", contentType: "application/zip", body: largeZip},
+		{name: "octet-stream KV value", acceptEncoding: "gzip", contentType: "application/octet-stream", body: largeJSON},
+		{name: "sniffed content type", acceptEncoding: "gzip", body: largeJSON, wantEncoding: "gzip"},
+		{name: "HEAD request", acceptEncoding: "gzip", method: http.MethodHead, contentType: "application/json", body: largeJSON},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := Compress(DefaultCompressMinSize)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tt.contentType != "" {
+					w.Header().Set("Content-Type", tt.contentType)
+				}
+				// Write in small chunks to exercise buffering across the threshold
+				for i := 0; i < len(tt.body); i += 100 {
+					w.Write(tt.body[i:min(i+100, len(tt.body))])
+				}
+			}))
+
+			method := tt.method
+			if method == "" {
+				method = http.MethodGet
+			}
+			req := httptest.NewRequest(method, "/kvlist/domain/example.com", nil)
+			if tt.acceptEncoding != "" {
+				req.Header.Set("Accept-Encoding", tt.acceptEncoding)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if got := rec.Header().Get("Content-Encoding"); got != tt.wantEncoding {
+				t.Fatalf("Expected Content-Encoding %q, got %q", tt.wantEncoding, got)
+			}
+			if !strings.Contains(rec.Header().Get("Vary"), "Accept-Encoding") {
+				t.Errorf("Expected Vary: Accept-Encoding")
+			}
+			if method == http.MethodHead {
+				return
+			}
+			got := decompress(t, tt.wantEncoding, rec.Body.Bytes())
+			if !bytes.Equal(got, tt.body) {
+				t.Errorf("Body mismatch after decompression: got %d bytes, want %d", len(got), len(tt.body))
+			}
+			if tt.wantEncoding != "" && rec.Body.Len() >= len(tt.body) {
+				t.Errorf("Expected compressed body to be smaller (%d >= %d)", rec.Body.Len(), len(tt.body))
+			}
+		})
+	}
+}
+
+func TestCompress_SkipsAlreadyEncoded(t *testing.T) {
+	gzipped := bytes.Repeat([]byte("x"), 4096)
+	handler := Compress(DefaultCompressMinSize)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Encoding", "gzip")
+		w.Write(gzipped)
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if !bytes.Equal(rec.Body.Bytes(), gzipped) {
+		t.Errorf("Expected pre-encoded body to pass through untouched")
+	}
+}
+
+func TestCompress_HeadersAndStatus(t *testing.T) {
+	body := strings.Repeat("body { color: red; }\n", 100)
+	handler := Compress(DefaultCompressMinSize)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/css")
+		w.Header().Set("Content-Length", "2100")
+		w.Header().Set("ETag", `"abc"`)
+		w.WriteHeader(http.StatusCreated)
+		io.WriteString(w, body)
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/css/app.css", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusCreated {
+		t.Errorf("Expected status 201 to be preserved, got %d", rec.Code)
+	}
+	if rec.Header().Get("Content-Length") != "" {
+		t.Errorf("Expected Content-Length to be removed when compressing")
+	}
+	if got := rec.Header().Get("ETag"); got != `W/"abc"` {
+		t.Errorf("Expected strong ETag to be weakened, got %q", got)
+	}
+	if got := string(decompress(t, "gzip", rec.Body.Bytes())); got != body {
+		t.Errorf("Body mismatch after decompression")
+	}
+}
+
+func TestCompress_NotModified(t *testing.T) {
+	handler := Compress(DefaultCompressMinSize)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotModified)
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusNotModified {
+		t.Errorf("Expected 304, got %d", rec.Code)
+	}
+	if rec.Header().Get("Content-Encoding") != "" || rec.Body.Len() != 0 {
+		t.Errorf("Expected 304 without body or encoding")
+	}
+}
+
+func TestCompress_FlushStreamsUncompressed(t *testing.T) {
+	handler := Compress(DefaultCompressMinSize)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		io.WriteString(w, "data: hello\n\n")
+		w.(http.Flusher).Flush()
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/events", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if !rec.Flushed {
+		t.Errorf("Expected Flush to reach the underlying writer")
+	}
+	if rec.Header().Get("Content-Encoding") != "" {
+		t.Errorf("Expected event stream not to be compressed")
+	}
+	if rec.Body.String() != "data: hello\n\n" {
+		t.Errorf("Unexpected body %q", rec.Body.String())
+	}
+}
+
+func TestCompress_WithLoggingCountsWireBytes(t *testing.T) {
+	body := strings.Repeat("a", 10000)
+	inner := Compress(DefaultCompressMinSize)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		io.WriteString(w, body)
+	}))
+
+	var written int64
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sw := NewStatusWriter(w)
+		inner.ServeHTTP(sw, r)
+		written = sw.BytesWritten()
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if written != int64(rec.Body.Len()) {
+		t.Errorf("Expected logged bytes (%d) to match compressed wire bytes (%d)", written, rec.Body.Len())
+	}
+}
+
+func TestNegotiateEncoding(t *testing.T) {
+	tests := map[string]string{
+		"":                    "",
+		"identity":            "",
+		"br":                  "",
+		"gzip":                "gzip",
+		"GZIP":                "gzip",
+		"deflate, gzip;q=0.5": "gzip",
+		"gzip;q=0":            "",
+		"gzip;q=0.0, deflate": "deflate",
+		"*;q=0.1":             "gzip",
+	}
+	for header, want := range tests {
+		if got := negotiateEncoding(header); got != want {
+			t.Errorf("negotiateEncoding(%q) = %q, want %q", header, got, want)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CORSOptions configures cross-origin access
+type CORSOptions struct {
+	// AllowedOrigins lists exact origins (scheme://host[:port]) that may make
+	// cross-origin requests. "*" allows any origin, but only without credentials.
+	AllowedOrigins []string
+
+	// AllowedMethods and AllowedHeaders are returned on preflight responses
+	AllowedMethods []string
+	AllowedHeaders []string
+
+	// AllowCredentials permits cookies on cross-origin requests
+	AllowCredentials bool
+
+	// MaxAge is how long browsers may cache a preflight response
+	MaxAge time.Duration
+}
+
+// CORS returns middleware that adds CORS headers for allowed origins and
+// answers preflight requests directly, so they never reach auth middleware.
+// Requests from other origins get no CORS headers, which makes the browser
+// block them without the server treating them as errors.
+func CORS(opts CORSOptions) func(http.Handler) http.Handler {
+	origins := make(map[string]bool, len(opts.AllowedOrigins))
+	anyOrigin := false
+	for _, origin := range opts.AllowedOrigins {
+		if origin == "*" {
+			anyOrigin = !opts.AllowCredentials
+			continue
+		}
+		origins[strings.TrimSuffix(origin, "/")] = true
+	}
+	methods := strings.Join(opts.AllowedMethods, ", ")
+	headers := strings.Join(opts.AllowedHeaders, ", ")
+	maxAge := strconv.Itoa(int(opts.MaxAge.Seconds()))
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			origin := r.Header.Get("Origin")
+			if origin == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			h := w.Header()
+			h.Add("Vary", "Origin")
+
+			allowed := anyOrigin || origins[origin]
+			preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+
+			if allowed {
+				if anyOrigin {
+					h.Set("Access-Control-Allow-Origin", "*")
+				} else {
+					h.Set("Access-Control-Allow-Origin", origin)
+				}
+				if opts.AllowCredentials {
+					h.Set("Access-Control-Allow-Credentials", "true")
+				}
+			}
+
+			if preflight {
+				h.Add("Vary", "Access-Control-Request-Method")
+				h.Add("Vary", "Access-Control-Request-Headers")
+				if allowed {
+					h.Set("Access-Control-Allow-Methods", methods)
+					if headers != "" {
+						h.Set("Access-Control-Allow-Headers", headers)
+					}
+					if opts.MaxAge > 0 {
+						h.Set("Access-Control-Max-Age", maxAge)
+					}
+				}
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
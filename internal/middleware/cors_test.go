@@ -0,0 +1,135 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCORS(t *testing.T) {
+	opts := CORSOptions{
+		AllowedOrigins:   []string{"https://client.example.com"},
+		AllowedMethods:   []string{"GET", "PUT", "DELETE"},
+		AllowedHeaders:   []string{"Content-Type"},
+		AllowCredentials: true,
+		MaxAge:           10 * time.Minute,
+	}
+
+	// The wrapped handler stands in for auth: it rejects everything, so any
+	// request that reaches it gets a 401.
+	handler := CORS(opts)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+	}))
+
+	tests := []struct {
+		name            string
+		method          string
+		origin          string
+		requestMethod   string
+		wantStatus      int
+		wantAllowOrigin string
+		wantCredentials string
+		wantMethods     string
+		wantMaxAge      string
+	}{
+		{
+			name:            "preflight from allowed origin",
+			method:          http.MethodOptions,
+			origin:          "https://client.example.com",
+			requestMethod:   "PUT",
+			wantStatus:      http.StatusNoContent,
+			wantAllowOrigin: "https://client.example.com",
+			wantCredentials: "true",
+			wantMethods:     "GET, PUT, DELETE",
+			wantMaxAge:      "600",
+		},
+		{
+			name:          "preflight from disallowed origin",
+			method:        http.MethodOptions,
+			origin:        "https://evil.example.com",
+			requestMethod: "PUT",
+			wantStatus:    http.StatusNoContent,
+		},
+		{
+			name:            "credentialed request from allowed origin",
+			method:          http.MethodGet,
+			origin:          "https://client.example.com",
+			wantStatus:      http.StatusUnauthorized,
+			wantAllowOrigin: "https://client.example.com",
+			wantCredentials: "true",
+		},
+		{
+			name:       "request from disallowed origin",
+			method:     http.MethodGet,
+			origin:     "https://evil.example.com",
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "same-origin request without Origin header",
+			method:     http.MethodGet,
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "plain OPTIONS is not a preflight",
+			method:     http.MethodOptions,
+			origin:     "https://client.example.com",
+			wantStatus: http.StatusUnauthorized,
+			// Still a cross-origin response, so the origin is echoed
+			wantAllowOrigin: "https://client.example.com",
+			wantCredentials: "true",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/kv/some/key", nil)
+			if tt.origin != "" {
+				req.Header.Set("Origin", tt.origin)
+			}
+			if tt.requestMethod != "" {
+				req.Header.Set("Access-Control-Request-Method", tt.requestMethod)
+			}
+
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("Expected status %d, got %d", tt.wantStatus, rec.Code)
+			}
+			h := rec.Header()
+			if got := h.Get("Access-Control-Allow-Origin"); got != tt.wantAllowOrigin {
+				t.Errorf("Expected Allow-Origin %q, got %q", tt.wantAllowOrigin, got)
+			}
+			if got := h.Get("Access-Control-Allow-Credentials"); got != tt.wantCredentials {
+				t.Errorf("Expected Allow-Credentials %q, got %q", tt.wantCredentials, got)
+			}
+			if got := h.Get("Access-Control-Allow-Methods"); got != tt.wantMethods {
+				t.Errorf("Expected Allow-Methods %q, got %q", tt.wantMethods, got)
+			}
+			if got := h.Get("Access-Control-Max-Age"); got != tt.wantMaxAge {
+				t.Errorf("Expected Max-Age %q, got %q", tt.wantMaxAge, got)
+			}
+		})
+	}
+}
+
+func TestCORS_Wildcard(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	req := httptest.NewRequest(http.MethodGet, "/kv/file/ab/cd/abcd", nil)
+	req.Header.Set("Origin", "https://anywhere.example.com")
+
+	rec := httptest.NewRecorder()
+	CORS(CORSOptions{AllowedOrigins: []string{"*"}})(next).ServeHTTP(rec, req)
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "*" {
+		t.Errorf("Expected wildcard Allow-Origin, got %q", got)
+	}
+
+	// Wildcard is ignored when credentials are allowed
+	rec = httptest.NewRecorder()
+	CORS(CORSOptions{AllowedOrigins: []string{"*"}, AllowCredentials: true})(next).ServeHTTP(rec, req)
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "" {
+		t.Errorf("Expected no Allow-Origin for wildcard with credentials, got %q", got)
+	}
+}
@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"net/http"
+	"time"
+)
+
+// Deadline returns middleware that replaces the server-wide read and write
+// deadlines for the requests it wraps, for routes such as large uploads or
+// long-lived streams that legitimately outlast http.Server's ReadTimeout
+// and WriteTimeout.
+//
+// http.Server sets the connection's write deadline when it starts reading a
+// request, so WriteTimeout caps the total time to read the body and write the
+// response, and streams such as Server-Sent Events are cut off mid-stream
+// once it passes. Extending the deadline here (via http.ResponseController)
+// applies only to the current request; the server resets it for the next one
+// on a kept-alive connection. A zero duration removes that deadline entirely,
+// so streaming handlers should still watch r.Context() to notice clients
+// that go away.
+//
+// ReadHeaderTimeout still applies: it elapses before any handler runs.
+func Deadline(read, write time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rc := http.NewResponseController(w)
+			// Errors mean the writer doesn't support deadlines (e.g. in
+			// tests); the server-wide timeouts then stay in force
+			rc.SetReadDeadline(deadline(read))
+			rc.SetWriteDeadline(deadline(write))
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// deadline converts a duration from now into a deadline, where zero means none
+func deadline(d time.Duration) time.Time {
+	if d <= 0 {
+		return time.Time{}
+	}
+	return time.Now().Add(d)
+}
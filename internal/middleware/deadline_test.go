@@ -0,0 +1,92 @@
+package middleware
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTimeoutServer starts a server whose timeouts are all short
+func newTimeoutServer(t *testing.T, handler http.Handler) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewUnstartedServer(handler)
+	srv.Config.ReadHeaderTimeout = 100 * time.Millisecond
+	srv.Config.ReadTimeout = 100 * time.Millisecond
+	srv.Config.WriteTimeout = 100 * time.Millisecond
+	srv.Start()
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// stream writes an SSE event every 50ms for longer than WriteTimeout
+var stream = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	for i := range 8 {
+		if _, err := fmt.Fprintf(w, "data: %d\n\n", i); err != nil {
+			return
+		}
+		w.(http.Flusher).Flush()
+		select {
+		case <-r.Context().Done():
+			return
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+})
+
+func countEvents(t *testing.T, url string) int {
+	t.Helper()
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body) // a cut-off stream ends in an error
+	return strings.Count(string(body), "data: ")
+}
+
+func TestDeadline_StreamOutlivesWriteTimeout(t *testing.T) {
+	srv := newTimeoutServer(t, Deadline(0, 0)(stream))
+
+	if got := countEvents(t, srv.URL); got != 8 {
+		t.Errorf("Expected all 8 events, got %d", got)
+	}
+}
+
+func TestDeadline_WithoutItStreamIsCut(t *testing.T) {
+	srv := newTimeoutServer(t, stream)
+
+	if got := countEvents(t, srv.URL); got >= 8 {
+		t.Errorf("Expected WriteTimeout to cut the stream short, got all %d events", got)
+	}
+}
+
+func TestDeadline_SlowHeaderIsCutOff(t *testing.T) {
+	srv := newTimeoutServer(t, Deadline(0, 0)(stream))
+
+	conn, err := net.Dial("tcp", srv.Listener.Addr().String())
+	if err != nil {
+		t.Fatalf("Failed to dial: %v", err)
+	}
+	defer conn.Close()
+
+	// Start a request but never finish its headers
+	if _, err := io.WriteString(conn, "GET / HTTP/1.1\r\nHost: example.com\r\n"); err != nil {
+		t.Fatalf("Failed to write: %v", err)
+	}
+
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	if strings.Contains(line, "200") {
+		t.Fatalf("Expected the request to be rejected, got %q", line)
+	}
+	if ne, ok := err.(net.Error); ok && ne.Timeout() {
+		t.Fatal("Expected the server to close the connection after ReadHeaderTimeout")
+	}
+}
@@ -0,0 +1,214 @@
+// Package middleware provides HTTP middleware shared by all routes.
+package middleware
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// LoggingOptions configures the request logging middleware
+type LoggingOptions struct {
+	// Logger receives the log records (defaults to slog.Default())
+	Logger *slog.Logger
+
+	// Identify returns the authenticated user for a request, or "" if anonymous.
+	// It is called after the handler has run.
+	Identify func(r *http.Request) string
+
+	// Quiet reports whether a request is routine (e.g. a static asset).
+	// Successful quiet requests are sampled instead of logged individually.
+	Quiet func(r *http.Request) bool
+
+	// SampleEvery logs one in every SampleEvery successful quiet requests.
+	// Zero or one logs them all.
+	SampleEvery int
+
+	// Combined, when set, receives one Combined Log Format line per request
+	// (for tools like goaccess) instead of a record on Logger. Each line is
+	// a single Write, so the writer must be safe for concurrent use.
+	Combined io.Writer
+}
+
+// Logging returns middleware that logs each request with its status code,
+// response size, duration, client address, and user (when known).
+// 4xx responses are logged at Warn and 5xx at Error.
+func Logging(opts LoggingOptions) func(http.Handler) http.Handler {
+	logger := opts.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+	var quietCount atomic.Uint64
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			rw := NewStatusWriter(w)
+			next.ServeHTTP(rw, r)
+			duration := time.Since(start)
+
+			status := rw.Status()
+			level := slog.LevelInfo
+			switch {
+			case status >= 500:
+				level = slog.LevelError
+			case status >= 400:
+				level = slog.LevelWarn
+			}
+
+			attrs := []slog.Attr{
+				slog.String("method", r.Method),
+				slog.String("path", r.URL.Path),
+				slog.Int("status", status),
+				slog.Int64("bytes", rw.BytesWritten()),
+				slog.Duration("duration", duration),
+				slog.String("remote", ClientIP(r)),
+			}
+
+			// Sample successful routine requests so they don't drown the log
+			if level == slog.LevelInfo && opts.Quiet != nil && opts.Quiet(r) && opts.SampleEvery > 1 {
+				if quietCount.Add(1)%uint64(opts.SampleEvery) != 1 {
+					return
+				}
+				attrs = append(attrs, slog.Int("sampled", opts.SampleEvery))
+			}
+
+			user := ""
+			if opts.Identify != nil {
+				user = opts.Identify(r)
+			}
+
+			if opts.Combined != nil {
+				opts.Combined.Write(combinedLine(r, rw, start, user))
+				return
+			}
+
+			if user != "" {
+				attrs = append(attrs, slog.String("user", user))
+			}
+			logger.LogAttrs(r.Context(), level, "HTTP request", attrs...)
+		})
+	}
+}
+
+// combinedLine formats a request in Combined Log Format:
+// host ident user [time] "request" status bytes "referer" "user-agent"
+func combinedLine(r *http.Request, rw *StatusWriter, start time.Time, user string) []byte {
+	if user == "" {
+		user = "-"
+	}
+	size := "-"
+	if n := rw.BytesWritten(); n > 0 {
+		size = strconv.FormatInt(n, 10)
+	}
+	uri := r.RequestURI
+	if uri == "" {
+		uri = r.URL.RequestURI()
+	}
+
+	return fmt.Appendf(nil, "%s - %s [%s] \"%s %s %s\" %d %s \"%s\" \"%s\"\n",
+		ClientIP(r),
+		clfEscape(user),
+		start.Format("02/Jan/2006:15:04:05 -0700"),
+		clfEscape(r.Method), clfEscape(uri), clfEscape(r.Proto),
+		rw.Status(),
+		size,
+		clfEscape(r.Referer()),
+		clfEscape(r.UserAgent()),
+	)
+}
+
+// clfEscape escapes quotes, backslashes, and control characters so a
+// client can't break the line structure of the access log
+func clfEscape(s string) string {
+	if s == "" {
+		return "-"
+	}
+	var b strings.Builder
+	for _, c := range []byte(s) {
+		switch {
+		case c == '"' || c == '\\':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case c < 0x20 || c == 0x7f:
+			fmt.Fprintf(&b, "\\x%02x", c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// StatusWriter records the status code and number of bytes written.
+// It passes Flush and Hijack through so streaming responses keep working,
+// and supports http.ResponseController via Unwrap.
+type StatusWriter struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+// NewStatusWriter wraps w to record its status code and size
+func NewStatusWriter(w http.ResponseWriter) *StatusWriter {
+	return &StatusWriter{ResponseWriter: w}
+}
+
+// Status returns the response status code (200 if the handler never set one)
+func (rw *StatusWriter) Status() int {
+	if rw.status == 0 {
+		return http.StatusOK
+	}
+	return rw.status
+}
+
+// BytesWritten returns the number of body bytes written
+func (rw *StatusWriter) BytesWritten() int64 {
+	return rw.bytes
+}
+
+func (rw *StatusWriter) WriteHeader(status int) {
+	if rw.status == 0 {
+		rw.status = status
+	}
+	rw.ResponseWriter.WriteHeader(status)
+}
+
+func (rw *StatusWriter) Write(b []byte) (int, error) {
+	if rw.status == 0 {
+		rw.status = http.StatusOK
+	}
+	n, err := rw.ResponseWriter.Write(b)
+	rw.bytes += int64(n)
+	return n, err
+}
+
+func (rw *StatusWriter) Flush() {
+	if rw.status == 0 {
+		rw.status = http.StatusOK
+	}
+	if f, ok := rw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (rw *StatusWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := rw.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("underlying ResponseWriter does not support hijacking")
+	}
+	if rw.status == 0 {
+		rw.status = http.StatusSwitchingProtocols
+	}
+	return h.Hijack()
+}
+
+func (rw *StatusWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
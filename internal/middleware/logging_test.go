@@ -0,0 +1,267 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// logRecords decodes JSON log lines written by a slog.JSONHandler
+func logRecords(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+	var records []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var record map[string]any
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("Failed to decode log line %q: %v", line, err)
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+func TestLogging_StatusAndSize(t *testing.T) {
+	tests := []struct {
+		name       string
+		handler    http.HandlerFunc
+		wantStatus float64
+		wantBytes  float64
+		wantLevel  string
+	}{
+		{
+			name: "implicit 200 from Write",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte("hello"))
+			},
+			wantStatus: 200,
+			wantBytes:  5,
+			wantLevel:  "INFO",
+		},
+		{
+			name:       "implicit 200 with no body",
+			handler:    func(w http.ResponseWriter, r *http.Request) {},
+			wantStatus: 200,
+			wantBytes:  0,
+			wantLevel:  "INFO",
+		},
+		{
+			name: "explicit 204",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusNoContent)
+			},
+			wantStatus: 204,
+			wantBytes:  0,
+			wantLevel:  "INFO",
+		},
+		{
+			name: "http.Error 404",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				http.Error(w, "Not found", http.StatusNotFound)
+			},
+			wantStatus: 404,
+			wantBytes:  float64(len("Not found\n")),
+			wantLevel:  "WARN",
+		},
+		{
+			name: "500",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusInternalServerError)
+				w.WriteHeader(http.StatusOK) // superfluous, must not change recorded status
+			},
+			wantStatus: 500,
+			wantBytes:  0,
+			wantLevel:  "ERROR",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			handler := Logging(LoggingOptions{
+				Logger: slog.New(slog.NewJSONHandler(&buf, nil)),
+			})(tt.handler)
+
+			req := httptest.NewRequest(http.MethodGet, "/kv/some/key", nil)
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+
+			records := logRecords(t, &buf)
+			if len(records) != 1 {
+				t.Fatalf("Expected 1 log record, got %d", len(records))
+			}
+			record := records[0]
+			if record["status"] != tt.wantStatus {
+				t.Errorf("Expected status %v, got %v", tt.wantStatus, record["status"])
+			}
+			if record["bytes"] != tt.wantBytes {
+				t.Errorf("Expected bytes %v, got %v", tt.wantBytes, record["bytes"])
+			}
+			if record["level"] != tt.wantLevel {
+				t.Errorf("Expected level %s, got %v", tt.wantLevel, record["level"])
+			}
+			if record["path"] != "/kv/some/key" {
+				t.Errorf("Expected path /kv/some/key, got %v", record["path"])
+			}
+		})
+	}
+}
+
+func TestLogging_Identity(t *testing.T) {
+	var buf bytes.Buffer
+	handler := Logging(LoggingOptions{
+		Logger: slog.New(slog.NewJSONHandler(&buf, nil)),
+		Identify: func(r *http.Request) string {
+			return r.Header.Get("X-Test-User")
+		},
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-Test-User", "alice@example.com")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	records := logRecords(t, &buf)
+	if len(records) != 2 {
+		t.Fatalf("Expected 2 log records, got %d", len(records))
+	}
+	if records[0]["user"] != "alice@example.com" {
+		t.Errorf("Expected user attribute, got %v", records[0]["user"])
+	}
+	if _, ok := records[1]["user"]; ok {
+		t.Errorf("Expected no user attribute for anonymous request")
+	}
+}
+
+func TestLogging_SamplesQuietRequests(t *testing.T) {
+	var buf bytes.Buffer
+	handler := Logging(LoggingOptions{
+		Logger:      slog.New(slog.NewJSONHandler(&buf, nil)),
+		Quiet:       func(r *http.Request) bool { return strings.HasPrefix(r.URL.Path, "/js/") },
+		SampleEvery: 10,
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "missing.js") {
+			http.NotFound(w, r)
+		}
+	}))
+
+	for i := 0; i < 25; i++ {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/js/app.js", nil))
+	}
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/js/missing.js", nil))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/kv/key", nil))
+
+	records := logRecords(t, &buf)
+	var sampled, missing, kv int
+	for _, record := range records {
+		switch record["path"] {
+		case "/js/app.js":
+			sampled++
+		case "/js/missing.js":
+			missing++
+		case "/kv/key":
+			kv++
+		}
+	}
+
+	// Requests 1, 11, and 21 are logged
+	if sampled != 3 {
+		t.Errorf("Expected 3 sampled static records, got %d", sampled)
+	}
+	if missing != 1 {
+		t.Errorf("Expected failed static request to always be logged, got %d", missing)
+	}
+	if kv != 1 {
+		t.Errorf("Expected non-quiet request to be logged, got %d", kv)
+	}
+}
+
+func TestLogging_PassesThroughFlusher(t *testing.T) {
+	var flushed bool
+	handler := Logging(LoggingOptions{
+		Logger: slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil)),
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := http.NewResponseController(w).Flush(); err != nil {
+			t.Errorf("Flush through wrapper failed: %v", err)
+		}
+		_, flushed = w.(http.Flusher)
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/events", nil))
+
+	if !flushed {
+		t.Errorf("Expected wrapped ResponseWriter to implement http.Flusher")
+	}
+	if !rec.Flushed {
+		t.Errorf("Expected underlying recorder to be flushed")
+	}
+}
+
+func TestLogging_HijackUnsupported(t *testing.T) {
+	handler := Logging(LoggingOptions{
+		Logger: slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil)),
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// httptest.ResponseRecorder can't hijack; the wrapper must report
+		// that as an error rather than panicking.
+		if _, _, err := w.(http.Hijacker).Hijack(); err == nil {
+			t.Errorf("Expected hijack error from non-hijackable writer")
+		}
+	}))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+}
+
+func TestLogging_CombinedFormat(t *testing.T) {
+	var slogBuf, combined bytes.Buffer
+	handler := Logging(LoggingOptions{
+		Logger: slog.New(slog.NewJSONHandler(&slogBuf, nil)),
+		Identify: func(r *http.Request) string {
+			return r.Header.Get("X-Test-User")
+		},
+		Combined: &combined,
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/empty" {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		w.Write([]byte("hello"))
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/kv/a?x=1", nil)
+	req.RemoteAddr = "203.0.113.9:5000"
+	req.Header.Set("X-Test-User", "alice@example.com")
+	req.Header.Set("Referer", "https://example.com/")
+	req.Header.Set("User-Agent", `Evil "agent"`+"\n"+`injected`)
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	req = httptest.NewRequest(http.MethodDelete, "/empty", nil)
+	req.RemoteAddr = "203.0.113.9:5000"
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	if slogBuf.Len() != 0 {
+		t.Errorf("Expected no slog records when writing combined format, got %q", slogBuf.String())
+	}
+
+	lines := strings.Split(strings.TrimSuffix(combined.String(), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 lines, got %q", combined.String())
+	}
+
+	first := lines[0]
+	if !strings.HasPrefix(first, "203.0.113.9 - alice@example.com [") {
+		t.Errorf("Unexpected line prefix: %q", first)
+	}
+	if !strings.HasSuffix(first, `] "GET /kv/a?x=1 HTTP/1.1" 200 5 "https://example.com/" "Evil \"agent\"\x0ainjected"`) {
+		t.Errorf("Unexpected line: %q", first)
+	}
+
+	if !strings.HasSuffix(lines[1], `] "DELETE /empty HTTP/1.1" 204 - "-" "-"`) || !strings.HasPrefix(lines[1], "203.0.113.9 - - [") {
+		t.Errorf("Unexpected line for anonymous empty response: %q", lines[1])
+	}
+}
@@ -0,0 +1,36 @@
+package middleware
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// Methods returns middleware that restricts a route to the given methods.
+// Other methods get 405 with an Allow header listing them, and OPTIONS gets
+// 204 with the same header. Neither reaches the wrapped handler, so a route
+// declares its methods once, and OPTIONS works without authentication.
+//
+// CORS preflights are answered earlier, by the CORS middleware.
+func Methods(methods ...string) func(http.Handler) http.Handler {
+	allowed := slices.Clone(methods)
+	if !slices.Contains(allowed, http.MethodOptions) {
+		allowed = append(allowed, http.MethodOptions)
+	}
+	allow := strings.Join(allowed, ", ")
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch {
+			case r.Method == http.MethodOptions:
+				w.Header().Set("Allow", allow)
+				w.WriteHeader(http.StatusNoContent)
+			case !slices.Contains(allowed, r.Method):
+				w.Header().Set("Allow", allow)
+				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			default:
+				next.ServeHTTP(w, r)
+			}
+		})
+	}
+}
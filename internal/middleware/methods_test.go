@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMethods(t *testing.T) {
+	called := false
+	handler := Methods(http.MethodGet, http.MethodPut)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+
+	tests := []struct {
+		method     string
+		wantStatus int
+		wantCalled bool
+	}{
+		{http.MethodGet, http.StatusOK, true},
+		{http.MethodPut, http.StatusOK, true},
+		{http.MethodPatch, http.StatusMethodNotAllowed, false},
+		{http.MethodOptions, http.StatusNoContent, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method, func(t *testing.T) {
+			called = false
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(tt.method, "/kv/x", nil))
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("Expected %d, got %d", tt.wantStatus, rec.Code)
+			}
+			if called != tt.wantCalled {
+				t.Errorf("Expected handler called=%v", tt.wantCalled)
+			}
+			allow := rec.Header().Get("Allow")
+			if tt.wantCalled && allow != "" {
+				t.Errorf("Expected no Allow header on success, got %q", allow)
+			}
+			if !tt.wantCalled && allow != "GET, PUT, OPTIONS" {
+				t.Errorf("Unexpected Allow header %q", allow)
+			}
+		})
+	}
+}
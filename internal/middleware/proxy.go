@@ -0,0 +1,159 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+type clientKey struct{}
+
+// client is the original client address and scheme of a request
+type client struct {
+	ip     string
+	scheme string
+}
+
+// ParseTrustedProxies parses CIDRs ("10.0.0.0/8") and single addresses
+// ("127.0.0.1", "::1") into prefixes
+func ParseTrustedProxies(entries []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(entries))
+	for _, entry := range entries {
+		if strings.Contains(entry, "/") {
+			prefix, err := netip.ParsePrefix(entry)
+			if err != nil {
+				return nil, fmt.Errorf("invalid trusted proxy %q: %w", entry, err)
+			}
+			prefixes = append(prefixes, prefix.Masked())
+			continue
+		}
+		addr, err := netip.ParseAddr(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %w", entry, err)
+		}
+		addr = addr.Unmap()
+		prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+	return prefixes, nil
+}
+
+// TrustedProxies returns middleware that determines each request's client
+// IP and scheme, available via ClientIP and Scheme.
+//
+// When the immediate peer is in trusted, the client IP is the rightmost
+// X-Forwarded-For hop that isn't itself a trusted proxy, and the scheme
+// comes from X-Forwarded-Proto. Otherwise both headers are ignored, so
+// clients can't spoof them by connecting directly.
+func TrustedProxies(trusted []netip.Prefix) func(http.Handler) http.Handler {
+	isTrusted := func(addr netip.Addr) bool {
+		for _, prefix := range trusted {
+			if prefix.Contains(addr) {
+				return true
+			}
+		}
+		return false
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			c := client{ip: remoteHost(r.RemoteAddr), scheme: "http"}
+			if r.TLS != nil {
+				c.scheme = "https"
+			}
+
+			if peer, ok := parseAddr(c.ip); ok && isTrusted(peer) {
+				if ip, ok := forwardedFor(r.Header.Values("X-Forwarded-For"), isTrusted); ok {
+					c.ip = ip.String()
+				}
+				if proto := forwardedProto(r.Header.Get("X-Forwarded-Proto")); proto != "" {
+					c.scheme = proto
+				}
+			}
+
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), clientKey{}, c)))
+		})
+	}
+}
+
+// forwardedFor walks X-Forwarded-For from the right, skipping trusted
+// proxies, and returns the first untrusted hop. If every hop is trusted the
+// leftmost one is returned. A malformed hop ends the walk, since nothing to
+// its left can be trusted.
+func forwardedFor(values []string, isTrusted func(netip.Addr) bool) (netip.Addr, bool) {
+	var hops []string
+	for _, v := range values {
+		hops = append(hops, strings.Split(v, ",")...)
+	}
+
+	var client netip.Addr
+	found := false
+	for i := len(hops) - 1; i >= 0; i-- {
+		addr, ok := parseAddr(strings.TrimSpace(hops[i]))
+		if !ok {
+			break
+		}
+		client, found = addr, true
+		if !isTrusted(addr) {
+			break
+		}
+	}
+	return client, found
+}
+
+// forwardedProto returns the scheme set by the nearest proxy, or ""
+func forwardedProto(header string) string {
+	if header == "" {
+		return ""
+	}
+	parts := strings.Split(header, ",")
+	proto := strings.ToLower(strings.TrimSpace(parts[len(parts)-1]))
+	if proto == "http" || proto == "https" {
+		return proto
+	}
+	return ""
+}
+
+// parseAddr parses an IP address, with or without a port or IPv6 brackets,
+// normalizing IPv4-mapped IPv6 addresses to IPv4
+func parseAddr(s string) (netip.Addr, bool) {
+	if addrPort, err := netip.ParseAddrPort(s); err == nil {
+		return addrPort.Addr().Unmap(), true
+	}
+	addr, err := netip.ParseAddr(strings.TrimSuffix(strings.TrimPrefix(s, "["), "]"))
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return addr.Unmap(), true
+}
+
+// remoteHost strips the port from a RemoteAddr
+func remoteHost(remoteAddr string) string {
+	if host, _, err := net.SplitHostPort(remoteAddr); err == nil {
+		return host
+	}
+	return remoteAddr
+}
+
+// ClientIP returns the original client IP of a request. Without the
+// TrustedProxies middleware it is the immediate peer's address.
+func ClientIP(r *http.Request) string {
+	if c, ok := r.Context().Value(clientKey{}).(client); ok {
+		return c.ip
+	}
+	return remoteHost(r.RemoteAddr)
+}
+
+// Scheme returns "https" or "http" for the original request. Without the
+// TrustedProxies middleware it reflects only the direct connection.
+func Scheme(r *http.Request) string {
+	if c, ok := r.Context().Value(clientKey{}).(client); ok {
+		return c.scheme
+	}
+	if r.TLS != nil {
+		return "https"
+	}
+	return "http"
+}
@@ -0,0 +1,180 @@
+package middleware
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestParseTrustedProxies(t *testing.T) {
+	prefixes, err := ParseTrustedProxies([]string{"10.0.0.0/8", "127.0.0.1", "::1", "fd00::/8", "192.168.1.7/24"})
+	if err != nil {
+		t.Fatalf("ParseTrustedProxies failed: %v", err)
+	}
+	want := []string{"10.0.0.0/8", "127.0.0.1/32", "::1/128", "fd00::/8", "192.168.1.0/24"}
+	for i, p := range prefixes {
+		if p.String() != want[i] {
+			t.Errorf("Prefix %d = %s, want %s", i, p, want[i])
+		}
+	}
+
+	for _, bad := range []string{"10.0.0.0/33", "not-an-ip", "10.0.0"} {
+		if _, err := ParseTrustedProxies([]string{bad}); err == nil {
+			t.Errorf("Expected error for %q", bad)
+		}
+	}
+}
+
+func TestTrustedProxies(t *testing.T) {
+	trusted, err := ParseTrustedProxies([]string{"10.0.0.0/8", "::1", "fd00::/8"})
+	if err != nil {
+		t.Fatalf("ParseTrustedProxies failed: %v", err)
+	}
+
+	var gotIP, gotScheme string
+	handler := TrustedProxies(trusted)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotIP, gotScheme = ClientIP(r), Scheme(r)
+	}))
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		xff        []string
+		proto      string
+		tls        bool
+		wantIP     string
+		wantScheme string
+	}{
+		{
+			name:       "direct client, no headers",
+			remoteAddr: "203.0.113.9:5000",
+			wantIP:     "203.0.113.9",
+			wantScheme: "http",
+		},
+		{
+			name:       "untrusted peer headers ignored",
+			remoteAddr: "203.0.113.9:5000",
+			xff:        []string{"198.51.100.1"},
+			proto:      "https",
+			wantIP:     "203.0.113.9",
+			wantScheme: "http",
+		},
+		{
+			name:       "trusted proxy",
+			remoteAddr: "10.0.0.2:5000",
+			xff:        []string{"198.51.100.1"},
+			proto:      "https",
+			wantIP:     "198.51.100.1",
+			wantScheme: "https",
+		},
+		{
+			name:       "chained, spoofed leftmost hop ignored",
+			remoteAddr: "10.0.0.2:5000",
+			xff:        []string{"1.2.3.4, 198.51.100.1, 10.0.0.3"},
+			wantIP:     "198.51.100.1",
+			wantScheme: "http",
+		},
+		{
+			name:       "chained across multiple headers",
+			remoteAddr: "10.0.0.2:5000",
+			xff:        []string{"1.2.3.4", "198.51.100.1", "10.0.0.3"},
+			wantIP:     "198.51.100.1",
+			wantScheme: "http",
+		},
+		{
+			name:       "all hops trusted",
+			remoteAddr: "10.0.0.2:5000",
+			xff:        []string{"10.0.0.5, 10.0.0.3"},
+			wantIP:     "10.0.0.5",
+			wantScheme: "http",
+		},
+		{
+			name:       "malformed hop stops the walk",
+			remoteAddr: "10.0.0.2:5000",
+			xff:        []string{"198.51.100.1, garbage, 10.0.0.3"},
+			wantIP:     "10.0.0.3",
+			wantScheme: "http",
+		},
+		{
+			name:       "trusted proxy, missing headers",
+			remoteAddr: "10.0.0.2:5000",
+			wantIP:     "10.0.0.2",
+			wantScheme: "http",
+		},
+		{
+			name:       "IPv6 proxy and client",
+			remoteAddr: "[::1]:5000",
+			xff:        []string{"2001:db8::1, fd00::2"},
+			proto:      "https",
+			wantIP:     "2001:db8::1",
+			wantScheme: "https",
+		},
+		{
+			name:       "IPv6 client with brackets and port",
+			remoteAddr: "[::1]:5000",
+			xff:        []string{"[2001:db8::1]:443"},
+			wantIP:     "2001:db8::1",
+			wantScheme: "http",
+		},
+		{
+			name:       "IPv4-mapped peer is trusted",
+			remoteAddr: "[::ffff:10.0.0.2]:5000",
+			xff:        []string{"198.51.100.1"},
+			wantIP:     "198.51.100.1",
+			wantScheme: "http",
+		},
+		{
+			name:       "nearest proto wins, bogus proto ignored",
+			remoteAddr: "10.0.0.2:5000",
+			proto:      "https, ftp",
+			wantIP:     "10.0.0.2",
+			wantScheme: "http",
+		},
+		{
+			name:       "direct TLS",
+			remoteAddr: "203.0.113.9:5000",
+			tls:        true,
+			wantIP:     "203.0.113.9",
+			wantScheme: "https",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = tt.remoteAddr
+			for _, v := range tt.xff {
+				req.Header.Add("X-Forwarded-For", v)
+			}
+			if tt.proto != "" {
+				req.Header.Set("X-Forwarded-Proto", tt.proto)
+			}
+			if tt.tls {
+				req.TLS = &tls.ConnectionState{}
+			}
+
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+
+			if gotIP != tt.wantIP {
+				t.Errorf("ClientIP = %q, want %q", gotIP, tt.wantIP)
+			}
+			if gotScheme != tt.wantScheme {
+				t.Errorf("Scheme = %q, want %q", gotScheme, tt.wantScheme)
+			}
+		})
+	}
+}
+
+func TestClientIP_WithoutMiddleware(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "203.0.113.9:5000"
+	req.Header.Set("X-Forwarded-For", "198.51.100.1")
+
+	if got := ClientIP(req); got != "203.0.113.9" {
+		t.Errorf("ClientIP = %q, want peer address", got)
+	}
+	if got := Scheme(req); got != "http" {
+		t.Errorf("Scheme = %q, want http", got)
+	}
+}
@@ -0,0 +1,82 @@
+package namegen
+
+import "fmt"
+
+// GenerateN creates n distinct adjective-noun combinations, each equally
+// likely to appear, skipping excluded names. It fails if fewer than n
+// acceptable names exist.
+func (g *Generator) GenerateN(n int) ([]string, error) {
+	if n < 0 {
+		return nil, fmt.Errorf("name count must not be negative, got %d", n)
+	}
+	space := len(Adjectives) * len(Nouns)
+	if n > space {
+		return nil, fmt.Errorf("asked for %d names but only %d combinations exist", n, space)
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	// When n is a small part of the space, drawing and discarding repeats is
+	// cheap. Near the whole space (or if exclusions cover much of it), that
+	// degrades, so shuffle the acceptable names instead.
+	if n <= space/2 {
+		if names, ok, err := g.sampleByRejection(n, space); err != nil || ok {
+			return names, err
+		}
+	}
+	return g.sampleByShuffle(n)
+}
+
+// sampleByRejection draws random combinations until n distinct acceptable
+// ones are found. It gives up (ok false) if that takes too many draws.
+func (g *Generator) sampleByRejection(n, space int) (names []string, ok bool, err error) {
+	seen := make(map[int]bool, n)
+	names = make([]string, 0, n)
+	for attempts := 0; len(names) < n; attempts++ {
+		if attempts >= maxAttempts*(n+1) {
+			return nil, false, nil
+		}
+		i, err := g.intN(space)
+		if err != nil {
+			return nil, false, err
+		}
+		if seen[i] {
+			continue
+		}
+		seen[i] = true
+		name := combination(i)
+		if !IsExcluded(name) {
+			names = append(names, name)
+		}
+	}
+	return names, true, nil
+}
+
+// sampleByShuffle lists every acceptable combination and picks n of them
+// with a partial Fisher-Yates shuffle
+func (g *Generator) sampleByShuffle(n int) ([]string, error) {
+	var all []string
+	for i := range len(Adjectives) * len(Nouns) {
+		if name := combination(i); !IsExcluded(name) {
+			all = append(all, name)
+		}
+	}
+	if n > len(all) {
+		return nil, fmt.Errorf("asked for %d names but only %d acceptable names exist", n, len(all))
+	}
+
+	for k := range n {
+		j, err := g.intN(len(all) - k)
+		if err != nil {
+			return nil, err
+		}
+		all[k], all[k+j] = all[k+j], all[k]
+	}
+	return all[:n], nil
+}
+
+// combination returns the i'th adjective-noun name
+func combination(i int) string {
+	return Adjectives[i/len(Nouns)] + "-" + Nouns[i%len(Nouns)]
+}
@@ -0,0 +1,86 @@
+package namegen
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGenerateN_Distinct(t *testing.T) {
+	for _, n := range []int{0, 1, 6, 100, 2000, 4000} {
+		names, err := GenerateN(n)
+		if err != nil {
+			t.Fatalf("GenerateN(%d) failed: %v", n, err)
+		}
+		if len(names) != n {
+			t.Fatalf("Expected %d names, got %d", n, len(names))
+		}
+		seen := make(map[string]bool)
+		for _, name := range names {
+			if seen[name] {
+				t.Fatalf("GenerateN(%d) returned %q twice", n, name)
+			}
+			seen[name] = true
+			if err := IsValidName(name); err != nil {
+				t.Errorf("GenerateN(%d) returned invalid name %q: %v", n, name, err)
+			}
+		}
+	}
+}
+
+func TestGenerateN_TooMany(t *testing.T) {
+	restoreWords(t)
+	setWords([]string{"jolly", "stout"}, []string{"otter", "walrus"})
+
+	if _, err := GenerateN(5); err == nil || !strings.Contains(err.Error(), "only 4 combinations") {
+		t.Errorf("Expected an error for more names than combinations, got %v", err)
+	}
+
+	// stout-walrus is excluded, leaving three
+	names, err := GenerateN(3)
+	if err != nil {
+		t.Fatalf("GenerateN(3) failed: %v", err)
+	}
+	for _, name := range names {
+		if name == "stout-walrus" {
+			t.Error("Expected excluded name to be skipped")
+		}
+	}
+	if _, err := GenerateN(4); err == nil || !strings.Contains(err.Error(), "only 3 acceptable") {
+		t.Errorf("Expected an error when exclusions shrink the space, got %v", err)
+	}
+	if _, err := GenerateN(-1); err == nil {
+		t.Error("Expected an error for a negative count")
+	}
+}
+
+func TestGenerateN_Distribution(t *testing.T) {
+	restoreWords(t)
+	setWords([]string{"bold", "jolly", "keen"}, []string{"otter", "panda", "yak"})
+
+	// Both the rejection (n=2) and shuffle (n=7) paths should pick every
+	// one of the 9 names equally often
+	for _, n := range []int{2, 7} {
+		g := NewSeededGenerator(uint64(n))
+		counts := make(map[string]int)
+		const batches = 9000
+		for range batches {
+			names, err := g.GenerateN(n)
+			if err != nil {
+				t.Fatalf("GenerateN(%d) failed: %v", n, err)
+			}
+			for _, name := range names {
+				counts[name]++
+			}
+		}
+
+		want := batches * n / 9
+		if len(counts) != 9 {
+			t.Errorf("n=%d: expected all 9 names, saw %d", n, len(counts))
+		}
+		for name, got := range counts {
+			if got < want*9/10 || got > want*11/10 {
+				t.Errorf("n=%d: %s appeared %d times, expected about %d", n, name, got, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package namegen
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// BrowserModulePath is where the server serves BrowserModule
+const BrowserModulePath = "/js/words.js"
+
+// BrowserModule returns an ES module exporting the current word lists and
+// exclusions, for the browser's name generator (web/js/namegen.js). Serving
+// it means the browser always uses the same lists as the server, including
+// a wordlist-dir override. Call it after LoadDir.
+func BrowserModule() []byte {
+	var b strings.Builder
+	b.WriteString("// Generated by the server from its display name word lists; do not edit.\n")
+	writeExport(&b, "ADJECTIVES", Adjectives)
+	writeExport(&b, "NOUNS", Nouns)
+	names := slices.Sorted(maps.Keys(excluded.names))
+	writeExport(&b, "EXCLUDED_NAMES", names)
+	writeExport(&b, "EXCLUDED_SUBSTRINGS", excluded.substrings)
+	return []byte(b.String())
+}
+
+// writeExport writes "export const name = [...];"
+func writeExport(b *strings.Builder, name string, words []string) {
+	if words == nil {
+		words = []string{}
+	}
+	list, _ := json.Marshal(words) // can't fail for strings
+	fmt.Fprintf(b, "export const %s = %s;\n", name, list)
+}
@@ -0,0 +1,32 @@
+package namegen
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBrowserModule(t *testing.T) {
+	defer setWords(Adjectives, Nouns)
+	defer func(ex exclusions) { excluded = ex }(excluded)
+	setWords([]string{"jolly", "keen"}, []string{"otter"})
+	excluded = exclusions{names: map[string]bool{"keen-otter": true, "jolly-otter": true}, substrings: []string{"lyo"}}
+
+	want := `// Generated by the server from its display name word lists; do not edit.
+export const ADJECTIVES = ["jolly","keen"];
+export const NOUNS = ["otter"];
+export const EXCLUDED_NAMES = ["jolly-otter","keen-otter"];
+export const EXCLUDED_SUBSTRINGS = ["lyo"];
+`
+	if got := string(BrowserModule()); got != want {
+		t.Errorf("BrowserModule() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestBrowserModule_BuiltinLists(t *testing.T) {
+	module := string(BrowserModule())
+	for _, word := range []string{Adjectives[0], Nouns[len(Nouns)-1]} {
+		if !strings.Contains(module, `"`+word+`"`) {
+			t.Errorf("Expected %q in the browser module", word)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package namegen
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ErrExcluded is returned by Validate for a name on the exclusion list.
+// Its message is neutral enough to show users as is.
+var ErrExcluded = errors.New("please pick another name")
+
+// exclusions are names that are never generated or accepted
+type exclusions struct {
+	names      map[string]bool // exact names
+	substrings []string        // matched against the name without hyphens
+}
+
+// excluded holds the exclusion list, loaded from words/excluded.txt at init
+// and optionally replaced by LoadDir
+var excluded exclusions
+
+// IsExcluded reports whether name is on the exclusion list
+func IsExcluded(name string) bool {
+	if excluded.names[name] {
+		return true
+	}
+	joined := strings.ReplaceAll(name, "-", "")
+	for _, sub := range excluded.substrings {
+		if strings.Contains(joined, sub) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseExclusions reads an exclusion list in the word list format. Entries
+// with hyphens are exact names; entries without are substrings.
+func parseExclusions(r io.Reader) (exclusions, error) {
+	ex := exclusions{names: make(map[string]bool)}
+	err := parseLines(r, func(entry string) error {
+		if !strings.Contains(entry, "-") {
+			if err := validateWord(entry); err != nil {
+				return err
+			}
+			ex.substrings = append(ex.substrings, entry)
+			return nil
+		}
+		for _, word := range strings.Split(entry, "-") {
+			if word == "" {
+				return fmt.Errorf("invalid name %q: empty word", entry)
+			}
+			if err := validateWord(word); err != nil {
+				return fmt.Errorf("invalid name %q: %w", entry, err)
+			}
+		}
+		ex.names[entry] = true
+		return nil
+	})
+	return ex, err
+}
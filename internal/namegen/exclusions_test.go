@@ -0,0 +1,105 @@
+package namegen
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestIsExcluded(t *testing.T) {
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"stout-walrus", true},
+		{"jolly-stout-walrus", false}, // exact entries match whole names only
+		{"dapper-panda", false},
+		{"stout-panda", false},
+		{"dumb-panda", true}, // substring
+		{"fanciful-turtle", false},
+		{"jolly-fat-cat", true},
+		{"clever-uglyduckling", true},
+	}
+
+	for _, tt := range tests {
+		if got := IsExcluded(tt.name); got != tt.want {
+			t.Errorf("IsExcluded(%q) = %v, expected %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestGenerate_NeverExcluded(t *testing.T) {
+	g := NewSeededGenerator(7)
+	for i := 0; i < 20000; i++ {
+		name, err := g.GenerateWords(MinWords + i%2)
+		if err != nil {
+			t.Fatalf("GenerateWords failed: %v", err)
+		}
+		if IsExcluded(name) {
+			t.Fatalf("Generated excluded name %q", name)
+		}
+	}
+}
+
+func TestGenerate_RedrawsExcluded(t *testing.T) {
+	restoreWords(t)
+	setWords([]string{"stout", "jolly"}, []string{"walrus"})
+
+	// Half of all draws are excluded; none may come out
+	g := NewSeededGenerator(1)
+	for i := 0; i < 100; i++ {
+		name, err := g.Generate()
+		if err != nil {
+			t.Fatalf("Generate failed: %v", err)
+		}
+		if name != "jolly-walrus" {
+			t.Fatalf("Expected only jolly-walrus, got %q", name)
+		}
+	}
+
+	// If every name is excluded, generation gives up instead of looping
+	setWords([]string{"stout"}, Nouns)
+	if _, err := g.Generate(); err == nil {
+		t.Error("Expected an error when every name is excluded")
+	}
+}
+
+func TestValidate_Excluded(t *testing.T) {
+	for _, name := range []string{"stout-walrus", "peculiar-monkey"} {
+		if err := IsValidName(name); !errors.Is(err, ErrExcluded) {
+			t.Errorf("Expected IsValidName(%q) to return ErrExcluded, got %v", name, err)
+		}
+	}
+	if err := IsValidName("jolly-walrus"); err != nil {
+		t.Errorf("Expected jolly-walrus to be valid, got %v", err)
+	}
+}
+
+func TestParseExclusions_Invalid(t *testing.T) {
+	for _, input := range []string{"Stout-walrus\n", "stout--walrus\n", "stout-\n", "bad word\n"} {
+		if _, err := parseExclusions(strings.NewReader(input)); err == nil {
+			t.Errorf("Expected %q to be rejected", input)
+		}
+	}
+}
+
+func TestLoadDir_Exclusions(t *testing.T) {
+	restoreWords(t)
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "excluded.txt"), []byte("jolly-otter\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := LoadDir(dir); err != nil {
+		t.Fatalf("LoadDir failed: %v", err)
+	}
+
+	if !IsExcluded("jolly-otter") {
+		t.Error("Expected the override list to apply")
+	}
+	if IsExcluded("stout-walrus") {
+		t.Error("Expected the override list to replace the built-in one")
+	}
+}
@@ -1,60 +1,140 @@
 package namegen
 
 import (
-	"crypto/rand"
+	crand "crypto/rand"
 	"fmt"
 	"math/big"
+	"math/rand/v2"
+	"slices"
+	"strings"
+	"sync"
 )
 
-// Lists of adjectives and nouns for generating display names
-// Adjectives have a Victorian/19th century literary flavor
-var Adjectives = []string{
-	"dapper", "jolly", "keen", "clever", "bold", "wise", "gallant", "stalwart",
-	"intrepid", "valiant", "earnest", "sprightly", "hale", "robust", "jaunty", "plucky",
-	"bonny", "dashing", "stout", "resolute", "steadfast", "vigilant", "mirthful", "sanguine",
-	"blithe", "jovial", "genial", "affable", "prudent", "sagacious", "wily", "canny",
-	"astute", "dauntless", "undaunted", "comely", "winsome", "droll", "whimsical", "fanciful",
-	"industrious", "diligent", "urbane", "refined", "courteous", "genteel", "spirited", "animated",
-	"vivacious", "formidable", "redoubtable", "singular", "peculiar", "quaint", "ardent", "fervent",
-	"hearty", "merry", "noble", "bright", "brisk", "capable", "worthy", "able",
-}
-
-var Nouns = []string{
-	"panda", "tiger", "eagle", "dolphin", "falcon", "turtle", "penguin", "raccoon",
-	"otter", "badger", "raven", "lynx", "beaver", "coyote", "gecko", "hamster",
-	"iguana", "jaguar", "koala", "lemur", "monkey", "narwhal", "owl", "parrot",
-	"quail", "rabbit", "salmon", "toucan", "unicorn", "viper", "walrus", "yak",
-	"zebra", "alpaca", "bison", "camel", "dragonfly", "elephant", "flamingo", "giraffe",
-	"hedgehog", "ibex", "jellyfish", "kangaroo", "llama", "meerkat", "nautilus", "octopus",
-	"platypus", "quokka", "starfish", "tapir", "urchin", "vulture", "wombat", "axolotl",
-	"butterfly", "chameleon", "firefly", "hummingbird", "mantis", "peacock", "seahorse", "sparrow",
+// Lists of adjectives and nouns for generating display names, loaded from
+// words/*.txt at init and optionally replaced by LoadDir
+var (
+	Adjectives []string
+	Nouns      []string
+)
+
+// MinWords and MaxWords bound the number of words in a display name
+const (
+	MinWords = 2
+	MaxWords = 3
+)
+
+// Generator produces display names from a source of randomness
+type Generator struct {
+	mu   sync.Mutex // guards intN, since seeded sources aren't concurrency-safe
+	intN func(n int) (int, error)
+}
+
+// defaultGenerator backs the package-level functions
+var defaultGenerator = NewGenerator()
+
+// NewGenerator returns a generator backed by crypto/rand, so names can't be
+// predicted
+func NewGenerator() *Generator {
+	return &Generator{intN: func(n int) (int, error) {
+		i, err := crand.Int(crand.Reader, big.NewInt(int64(n)))
+		if err != nil {
+			return 0, fmt.Errorf("failed to generate random number: %w", err)
+		}
+		return int(i.Int64()), nil
+	}}
+}
+
+// NewSeededGenerator returns a generator that yields the same sequence of
+// names for the same seed, for tests and reproducible fixtures. Its names
+// are predictable; never use it for anything user-facing.
+func NewSeededGenerator(seed uint64) *Generator {
+	rng := rand.New(rand.NewPCG(seed, seed))
+	return &Generator{intN: func(n int) (int, error) {
+		return rng.IntN(n), nil
+	}}
 }
 
 // Generate creates a random adjective-noun combination
 func Generate() (string, error) {
-	adj, err := randomChoice(Adjectives)
-	if err != nil {
-		return "", err
+	return defaultGenerator.Generate()
+}
+
+// GenerateWords creates a random name of count words: count-1 distinct
+// adjectives followed by a noun, e.g. "jolly-intrepid-otter"
+func GenerateWords(count int) (string, error) {
+	return defaultGenerator.GenerateWords(count)
+}
+
+// GenerateN creates n distinct adjective-noun combinations
+func GenerateN(n int) ([]string, error) {
+	return defaultGenerator.GenerateN(n)
+}
+
+// Generate creates a random adjective-noun combination
+func (g *Generator) Generate() (string, error) {
+	return g.GenerateWords(MinWords)
+}
+
+// maxAttempts bounds how often GenerateWords redraws a name that is on the
+// exclusion list
+const maxAttempts = 100
+
+// GenerateWords creates a random name of count words: count-1 distinct
+// adjectives followed by a noun. Names on the exclusion list are redrawn.
+func (g *Generator) GenerateWords(count int) (string, error) {
+	if count < MinWords || count > MaxWords {
+		return "", fmt.Errorf("word count must be between %d and %d, got %d", MinWords, MaxWords, count)
+	}
+	if len(Adjectives) < count-1 {
+		return "", fmt.Errorf("need %d adjectives for a %d-word name, have %d", count-1, count, len(Adjectives))
 	}
 
-	noun, err := randomChoice(Nouns)
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	for range maxAttempts {
+		name, err := g.draw(count)
+		if err != nil {
+			return "", err
+		}
+		if !IsExcluded(name) {
+			return name, nil
+		}
+	}
+	return "", fmt.Errorf("no acceptable name after %d attempts; check the exclusion list", maxAttempts)
+}
+
+// draw picks one name of count words. g.mu must be held.
+func (g *Generator) draw(count int) (string, error) {
+	words := make([]string, 0, count)
+	for len(words) < count-1 {
+		adj, err := g.choose(Adjectives)
+		if err != nil {
+			return "", err
+		}
+		if !slices.Contains(words, adj) {
+			words = append(words, adj)
+		}
+	}
+
+	noun, err := g.choose(Nouns)
 	if err != nil {
 		return "", err
 	}
 
-	return fmt.Sprintf("%s-%s", adj, noun), nil
+	return strings.Join(append(words, noun), "-"), nil
 }
 
-// randomChoice selects a random element from a slice using crypto/rand
-func randomChoice(items []string) (string, error) {
+// choose selects a random element from a slice. g.mu must be held.
+func (g *Generator) choose(items []string) (string, error) {
 	if len(items) == 0 {
 		return "", fmt.Errorf("empty slice")
 	}
 
-	n, err := rand.Int(rand.Reader, big.NewInt(int64(len(items))))
+	i, err := g.intN(len(items))
 	if err != nil {
-		return "", fmt.Errorf("failed to generate random number: %w", err)
+		return "", err
 	}
 
-	return items[n.Int64()], nil
+	return items[i], nil
 }
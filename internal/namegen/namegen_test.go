@@ -1,6 +1,7 @@
 package namegen
 
 import (
+	"slices"
 	"strings"
 	"testing"
 )
@@ -67,3 +68,75 @@ func TestGenerate(t *testing.T) {
 		}
 	}
 }
+
+func TestGenerateWords(t *testing.T) {
+	for _, count := range []int{2, 3} {
+		for i := 0; i < 100; i++ {
+			name, err := GenerateWords(count)
+			if err != nil {
+				t.Fatalf("GenerateWords(%d) failed: %v", count, err)
+			}
+			parts := strings.Split(name, "-")
+			if len(parts) != count {
+				t.Fatalf("Expected %d words, got %q", count, name)
+			}
+			if err := IsValidName(name); err != nil {
+				t.Errorf("Generated name %q doesn't validate: %v", name, err)
+			}
+			if count == 3 && parts[0] == parts[1] {
+				t.Errorf("Expected distinct adjectives, got %q", name)
+			}
+		}
+	}
+
+	for _, count := range []int{0, 1, 4} {
+		if _, err := GenerateWords(count); err == nil {
+			t.Errorf("Expected GenerateWords(%d) to fail", count)
+		}
+	}
+}
+
+func TestSeededGenerator(t *testing.T) {
+	sequence := func(seed uint64) []string {
+		g := NewSeededGenerator(seed)
+		var names []string
+		for i := 0; i < 20; i++ {
+			count := MinWords + i%2
+			name, err := g.GenerateWords(count)
+			if err != nil {
+				t.Fatalf("GenerateWords(%d) failed: %v", count, err)
+			}
+			names = append(names, name)
+		}
+		return names
+	}
+
+	a, b := sequence(42), sequence(42)
+	if !slices.Equal(a, b) {
+		t.Errorf("Expected the same seed to give the same names:\n%v\n%v", a, b)
+	}
+	if c := sequence(43); slices.Equal(a, c) {
+		t.Errorf("Expected different seeds to diverge, both gave %v", a)
+	}
+}
+
+func TestNewGenerator_CoversFullRange(t *testing.T) {
+	g := NewGenerator()
+	const n = 64
+	seen := make(map[int]int)
+
+	// 64 * 100 draws; missing any index has probability ~64 * (63/64)^6400
+	for i := 0; i < n*100; i++ {
+		v, err := g.intN(n)
+		if err != nil {
+			t.Fatalf("intN failed: %v", err)
+		}
+		if v < 0 || v >= n {
+			t.Fatalf("Expected index in [0, %d), got %d", n, v)
+		}
+		seen[v]++
+	}
+	if len(seen) != n {
+		t.Errorf("Expected all %d indexes, saw %d", n, len(seen))
+	}
+}
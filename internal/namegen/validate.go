@@ -0,0 +1,63 @@
+package namegen
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Lookup sets for the loaded word lists, maintained by setWords
+var (
+	adjectiveSet map[string]bool
+	nounSet      map[string]bool
+)
+
+// ErrWordCount is returned (wrapped) by Parse for a name with too few or
+// too many words
+var ErrWordCount = fmt.Errorf("name must have %d or %d hyphen-separated words", MinWords, MaxWords)
+
+// UnknownWordError is returned by Parse for a word that isn't on the list
+// its position requires
+type UnknownWordError struct {
+	Position int    // 1-based
+	Word     string // normalized to lowercase
+	Kind     string // "adjective" or "noun"
+}
+
+func (e *UnknownWordError) Error() string {
+	return fmt.Sprintf("word %d (%q) is not a known %s", e.Position, e.Word, e.Kind)
+}
+
+// Parse splits a display name into its words, lowercased and with
+// surrounding whitespace removed, and checks each against the loaded word
+// lists: every word but the last must be an adjective and the last a noun.
+// Errors are ErrWordCount (wrapped) or *UnknownWordError.
+func Parse(name string) ([]string, error) {
+	parts := strings.Split(strings.ToLower(strings.TrimSpace(name)), "-")
+	if len(parts) < MinWords || len(parts) > MaxWords {
+		return nil, fmt.Errorf("%w, got %d", ErrWordCount, len(parts))
+	}
+
+	last := len(parts) - 1
+	for i, word := range parts[:last] {
+		if !adjectiveSet[word] {
+			return nil, &UnknownWordError{Position: i + 1, Word: word, Kind: "adjective"}
+		}
+	}
+	if !nounSet[parts[last]] {
+		return nil, &UnknownWordError{Position: last + 1, Word: parts[last], Kind: "noun"}
+	}
+	return parts, nil
+}
+
+// IsValidName checks that name parses (see Parse) and isn't on the
+// exclusion list, in which case it returns ErrExcluded
+func IsValidName(name string) error {
+	parts, err := Parse(name)
+	if err != nil {
+		return err
+	}
+	if IsExcluded(strings.Join(parts, "-")) {
+		return ErrExcluded
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package namegen
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name      string
+		wantParts []string
+		wantCount bool   // expect ErrWordCount
+		wantWord  string // expect *UnknownWordError for this word
+		wantPos   int
+		wantKind  string
+	}{
+		{name: "dapper-panda", wantParts: []string{"dapper", "panda"}},
+		{name: "jolly-intrepid-otter", wantParts: []string{"jolly", "intrepid", "otter"}},
+		{name: "jolly-jolly-otter", wantParts: []string{"jolly", "jolly", "otter"}},
+
+		// Casing and surrounding whitespace are normalized
+		{name: "Dapper-Panda", wantParts: []string{"dapper", "panda"}},
+		{name: "JOLLY-intrepid-OtTeR", wantParts: []string{"jolly", "intrepid", "otter"}},
+		{name: "  dapper-panda\n", wantParts: []string{"dapper", "panda"}},
+
+		// Wrong word count
+		{name: "panda", wantCount: true},
+		{name: "", wantCount: true},
+		{name: "jolly-bold-keen-otter", wantCount: true},
+
+		// Unknown words, by position
+		{name: "panda-panda", wantWord: "panda", wantPos: 1, wantKind: "adjective"},
+		{name: "jolly-panda-otter", wantWord: "panda", wantPos: 2, wantKind: "adjective"},
+		{name: "jolly-keen", wantWord: "keen", wantPos: 2, wantKind: "noun"},
+		{name: "jolly-keen-bold", wantWord: "bold", wantPos: 3, wantKind: "noun"},
+		{name: "dapper--panda", wantWord: "", wantPos: 2, wantKind: "adjective"},
+		{name: "dapper-pan da", wantWord: "pan da", wantPos: 2, wantKind: "noun"},
+		{name: "dapper_panda-otter", wantWord: "dapper_panda", wantPos: 1, wantKind: "adjective"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parts, err := Parse(tt.name)
+
+			switch {
+			case tt.wantCount:
+				if !errors.Is(err, ErrWordCount) {
+					t.Errorf("Expected ErrWordCount, got %v", err)
+				}
+			case tt.wantKind != "":
+				var unknown *UnknownWordError
+				if !errors.As(err, &unknown) {
+					t.Fatalf("Expected *UnknownWordError, got %v", err)
+				}
+				if unknown.Word != tt.wantWord || unknown.Position != tt.wantPos || unknown.Kind != tt.wantKind {
+					t.Errorf("Expected %s %q at %d, got %s %q at %d",
+						tt.wantKind, tt.wantWord, tt.wantPos, unknown.Kind, unknown.Word, unknown.Position)
+				}
+			default:
+				if err != nil {
+					t.Fatalf("Expected %q to parse, got %v", tt.name, err)
+				}
+				if !slices.Equal(parts, tt.wantParts) {
+					t.Errorf("Expected %v, got %v", tt.wantParts, parts)
+				}
+			}
+		})
+	}
+}
+
+func TestIsValidName(t *testing.T) {
+	if err := IsValidName("Jolly-Otter"); err != nil {
+		t.Errorf("Expected mixed case name to be valid, got %v", err)
+	}
+	if err := IsValidName("Stout-Walrus"); !errors.Is(err, ErrExcluded) {
+		t.Errorf("Expected exclusions to apply after normalizing, got %v", err)
+	}
+	if err := IsValidName("jolly"); !errors.Is(err, ErrWordCount) {
+		t.Errorf("Expected ErrWordCount, got %v", err)
+	}
+}
+
+func BenchmarkIsValidName(b *testing.B) {
+	for b.Loop() {
+		IsValidName("able-sparrow")
+	}
+}
+
+// Word lookup through the sets, against the linear scan of the word lists
+// that validation used before. "able" and "sparrow" are last in their lists.
+func BenchmarkWordLookup(b *testing.B) {
+	b.Run("set", func(b *testing.B) {
+		for b.Loop() {
+			_ = adjectiveSet["able"] && nounSet["sparrow"]
+		}
+	})
+	b.Run("scan", func(b *testing.B) {
+		for b.Loop() {
+			_ = slices.Contains(Adjectives, "able") && slices.Contains(Nouns, "sparrow")
+		}
+	})
+}
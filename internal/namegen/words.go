@@ -0,0 +1,165 @@
+package namegen
+
+import (
+	"bufio"
+	"embed"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"strings"
+)
+
+//go:embed words/*.txt
+var wordFiles embed.FS
+
+// Word list file names, both in the embedded words/ directory and in an
+// override directory
+const (
+	adjectivesFile = "adjectives.txt"
+	nounsFile      = "nouns.txt"
+	excludedFile   = "excluded.txt"
+)
+
+func init() {
+	adjectives, err := loadWords(wordFiles, "words/"+adjectivesFile)
+	if err != nil {
+		panic(err)
+	}
+	nouns, err := loadWords(wordFiles, "words/"+nounsFile)
+	if err != nil {
+		panic(err)
+	}
+	setWords(adjectives, nouns)
+	if excluded, err = loadExclusions(wordFiles, "words/"+excludedFile); err != nil {
+		panic(err)
+	}
+}
+
+// ParseWords reads a word list: one word per line, with blank lines and
+// lines starting with # ignored. Words must be lowercase a-z (hyphens
+// separate the words of a name) and appear only once.
+func ParseWords(r io.Reader) ([]string, error) {
+	var words []string
+	seen := make(map[string]bool)
+	err := parseLines(r, func(word string) error {
+		if err := validateWord(word); err != nil {
+			return err
+		}
+		if seen[word] {
+			return fmt.Errorf("duplicate word %q", word)
+		}
+		seen[word] = true
+		words = append(words, word)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(words) == 0 {
+		return nil, errors.New("no words")
+	}
+	return words, nil
+}
+
+// parseLines calls add for each entry in a list file, skipping blank lines
+// and # comments, and reports the line number of the first bad entry
+func parseLines(r io.Reader, add func(entry string) error) error {
+	scanner := bufio.NewScanner(r)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		entry := strings.TrimSpace(scanner.Text())
+		if entry == "" || strings.HasPrefix(entry, "#") {
+			continue
+		}
+		if err := add(entry); err != nil {
+			return fmt.Errorf("line %d: %w", lineNum, err)
+		}
+	}
+	return scanner.Err()
+}
+
+// validateWord checks that word is lowercase ASCII letters
+func validateWord(word string) error {
+	for _, r := range word {
+		if r < 'a' || r > 'z' {
+			return fmt.Errorf("invalid word %q: only lowercase a-z allowed", word)
+		}
+	}
+	return nil
+}
+
+// LoadDir replaces the word lists and exclusion list with adjectives.txt,
+// nouns.txt, and excluded.txt from dir. Any of them may be missing, in
+// which case the built-in list is kept. It must be called before any names
+// are generated or validated, since the lists aren't guarded for concurrent
+// use.
+func LoadDir(dir string) error {
+	fsys := os.DirFS(dir)
+	adjectives, err := loadWords(fsys, adjectivesFile)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("word list override in %s: %w", dir, err)
+	}
+	nouns, err := loadWords(fsys, nounsFile)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("word list override in %s: %w", dir, err)
+	}
+	ex, err := loadExclusions(fsys, excludedFile)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("word list override in %s: %w", dir, err)
+	}
+
+	if adjectives == nil {
+		adjectives = Adjectives
+	}
+	if nouns == nil {
+		nouns = Nouns
+	}
+	setWords(adjectives, nouns)
+	if ex.names != nil {
+		excluded = ex
+	}
+	return nil
+}
+
+// setWords installs word lists and the lookup sets IsValidName uses
+func setWords(adjectives, nouns []string) {
+	Adjectives, Nouns = adjectives, nouns
+	adjectiveSet, nounSet = toSet(adjectives), toSet(nouns)
+}
+
+func toSet(words []string) map[string]bool {
+	set := make(map[string]bool, len(words))
+	for _, w := range words {
+		set[w] = true
+	}
+	return set
+}
+
+func loadWords(fsys fs.FS, name string) ([]string, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	words, err := ParseWords(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return words, nil
+}
+
+func loadExclusions(fsys fs.FS, name string) (exclusions, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return exclusions{}, err
+	}
+	defer f.Close()
+
+	ex, err := parseExclusions(f)
+	if err != nil {
+		return exclusions{}, fmt.Errorf("%s: %w", name, err)
+	}
+	return ex, nil
+}
@@ -0,0 +1,113 @@
+package namegen
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseWords(t *testing.T) {
+	input := "# A comment\n\nbold\r\n  keen  \n# another\nwise\n"
+	words, err := ParseWords(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("ParseWords failed: %v", err)
+	}
+	if want := []string{"bold", "keen", "wise"}; !slices.Equal(words, want) {
+		t.Errorf("Expected %v, got %v", want, words)
+	}
+}
+
+func TestParseWords_Invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		wantErr string
+	}{
+		{"uppercase", "bold\nKeen\n", "line 2: invalid word \"Keen\""},
+		{"hyphen", "well-read\n", "line 1: invalid word \"well-read\""},
+		{"digit", "r2d2\n", "invalid word"},
+		{"non-ascii", "café\n", "invalid word"},
+		{"inner space", "very bold\n", "invalid word"},
+		{"duplicate", "bold\nkeen\nbold\n", "line 3: duplicate word \"bold\""},
+		{"empty", "# only comments\n\n", "no words"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseWords(strings.NewReader(tt.input))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestEmbeddedWords(t *testing.T) {
+	if len(Adjectives) != 64 || len(Nouns) != 64 {
+		t.Errorf("Expected 64 adjectives and 64 nouns, got %d and %d", len(Adjectives), len(Nouns))
+	}
+}
+
+// restoreWords puts the built-in lists back after a test replaces them
+func restoreWords(t *testing.T) {
+	adjectives, nouns, ex := Adjectives, Nouns, excluded
+	t.Cleanup(func() {
+		setWords(adjectives, nouns)
+		excluded = ex
+	})
+}
+
+func TestLoadDir_OverridePrecedence(t *testing.T) {
+	restoreWords(t)
+	builtinAdjectives := Adjectives
+
+	// Only nouns are overridden; adjectives keep the built-in list
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "nouns.txt"), []byte("# Space theme\ncomet\nnebula\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := LoadDir(dir); err != nil {
+		t.Fatalf("LoadDir failed: %v", err)
+	}
+	if want := []string{"comet", "nebula"}; !slices.Equal(Nouns, want) {
+		t.Errorf("Expected nouns %v, got %v", want, Nouns)
+	}
+	if !slices.Equal(Adjectives, builtinAdjectives) {
+		t.Error("Expected adjectives to keep the built-in list")
+	}
+
+	// Validation and generation use the loaded lists
+	if err := IsValidName("bold-comet"); err != nil {
+		t.Errorf("Expected override noun to validate: %v", err)
+	}
+	if err := IsValidName("bold-panda"); err == nil {
+		t.Error("Expected built-in noun to be rejected after override")
+	}
+	name, err := NewSeededGenerator(1).Generate()
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if !strings.HasSuffix(name, "-comet") && !strings.HasSuffix(name, "-nebula") {
+		t.Errorf("Expected a name from the override list, got %q", name)
+	}
+}
+
+func TestLoadDir_InvalidOverrideKeepsLists(t *testing.T) {
+	restoreWords(t)
+	nouns := Nouns
+
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "adjectives.txt"), []byte("bold\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "nouns.txt"), []byte("Comet\n"), 0644)
+
+	err := LoadDir(dir)
+	if err == nil || !strings.Contains(err.Error(), "nouns.txt: line 1") {
+		t.Fatalf("Expected an error naming the bad file and line, got %v", err)
+	}
+	if !slices.Equal(Nouns, nouns) || len(Adjectives) == 1 {
+		t.Error("Expected a failed load to leave both lists unchanged")
+	}
+}
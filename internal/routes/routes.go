@@ -0,0 +1,55 @@
+// Package routes registers the API and KV endpoints, so the server and the
+// client tests run them wired identically.
+package routes
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/zellyn/trifle/internal/auth"
+	"github.com/zellyn/trifle/internal/buildinfo"
+	"github.com/zellyn/trifle/internal/kv"
+	"github.com/zellyn/trifle/internal/maintenance"
+	"github.com/zellyn/trifle/internal/middleware"
+)
+
+// API holds what the API and KV endpoints need
+type API struct {
+	Sessions    *auth.SessionManager
+	Maintenance *maintenance.Mode
+	KV          *kv.Handlers
+
+	// UploadTimeout replaces the server-wide timeouts for /kv/, so blob
+	// uploads and downloads aren't cut off; zero leaves them alone
+	UploadTimeout time.Duration
+}
+
+// Register adds the endpoints to mux. Each declares its methods; others get
+// 405, and OPTIONS gets 204, both with an Allow header and before any auth
+// check. Maintenance mode is applied around the whole mux by the caller.
+func (a API) Register(mux *http.ServeMux) {
+	readOnly := middleware.Methods(http.MethodGet, http.MethodHead)
+	postOnly := middleware.Methods(http.MethodPost)
+	kvMethods := middleware.Methods(http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete)
+
+	mux.Handle("/api/whoami", readOnly(auth.HandleWhoAmI(a.Sessions)))
+	mux.Handle("/api/status", readOnly(http.HandlerFunc(a.Maintenance.HandleStatus)))
+	mux.Handle("/api/version", readOnly(http.HandlerFunc(buildinfo.HandleVersion)))
+
+	requireAuth := kv.RequireAuth(kv.NewSessionManagerAdapter(func(r *http.Request) (string, bool, error) {
+		session, err := a.Sessions.GetSession(r)
+		if err != nil {
+			return "", false, err
+		}
+		return session.Email, session.Authenticated, nil
+	}))
+
+	var kvHandler http.Handler = requireAuth(a.KV.HandleKV)
+	if a.UploadTimeout > 0 {
+		kvHandler = middleware.Deadline(a.UploadTimeout, a.UploadTimeout)(kvHandler)
+	}
+	mux.Handle("/kv/", kvMethods(kvHandler))
+	mux.Handle("/kvlist/", readOnly(requireAuth(a.KV.HandleList)))
+	mux.Handle("/kvsync/manifest", postOnly(requireAuth(a.KV.HandleManifest)))
+	mux.Handle("/kvexists", postOnly(requireAuth(a.KV.HandleExists)))
+}
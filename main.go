@@ -3,18 +3,36 @@ package main
 import (
 	"context"
 	"embed"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"io/fs"
 	"log/slog"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"strings"
 	"syscall"
 	"time"
 
+	"github.com/zellyn/trifle/internal/apidoc"
+	"github.com/zellyn/trifle/internal/assets"
 	"github.com/zellyn/trifle/internal/auth"
+	"github.com/zellyn/trifle/internal/buildinfo"
+	"github.com/zellyn/trifle/internal/config"
+	"github.com/zellyn/trifle/internal/diag"
+	"github.com/zellyn/trifle/internal/health"
+	"github.com/zellyn/trifle/internal/jobs"
 	"github.com/zellyn/trifle/internal/kv"
+	"github.com/zellyn/trifle/internal/logfile"
+	"github.com/zellyn/trifle/internal/maintenance"
+	"github.com/zellyn/trifle/internal/metrics"
+	"github.com/zellyn/trifle/internal/middleware"
+	"github.com/zellyn/trifle/internal/namegen"
+	"github.com/zellyn/trifle/internal/routes"
 )
 
 //go:embed web
@@ -27,144 +45,360 @@ func main() {
 	}))
 	slog.SetDefault(logger)
 
-	// Get port from environment or default to 3000
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "3000"
+	// "trifle", "trifle -flags...", and "trifle serve" run the server;
+	// anything else is an admin command
+	args := os.Args[1:]
+	if len(args) > 0 && (args[0] == "-version" || args[0] == "--version") {
+		fmt.Println(buildinfo.Get())
+		return
+	}
+	if len(args) > 0 && args[0] == "serve" {
+		args = args[1:]
+	} else if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		os.Exit(runCommand(args, os.Getenv, os.Stdout, os.Stderr))
 	}
 
-	// Get OAuth redirect URL (used to determine if we're in production)
-	redirectURL := os.Getenv("OAUTH_REDIRECT_URL")
-	if redirectURL == "" {
-		// Default to localhost if not specified
-		redirectURL = fmt.Sprintf("http://localhost:%s/auth/callback", port)
+	// Load configuration from flags, environment, and optional config file
+	cfg, err := config.Load(args, os.Getenv)
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(0)
+		}
+		slog.Error("Invalid configuration", "error", err)
+		os.Exit(1)
 	}
 
-	// Determine if we're in production based on redirect URL scheme
-	isProduction := strings.HasPrefix(redirectURL, "https://")
+	if cfg.PrintConfig {
+		cfg.Print(os.Stdout)
+		if err := cfg.Validate(); err != nil {
+			slog.Error("Invalid configuration", "error", err)
+			os.Exit(1)
+		}
+		return
+	}
 
-	// Data directory for flat-file storage
-	dataDir := "./data"
+	// Run until interrupted, then shut down gracefully
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
 
-	// Initialize KV store
-	kvStore, err2 := kv.NewStore(dataDir)
-	if err2 != nil {
-		slog.Error("Failed to initialize KV store", "error", err2)
+	if err := run(ctx, cfg, nil); err != nil {
+		slog.Error("Server failed", "error", err)
 		os.Exit(1)
 	}
+}
+
+// run builds the application from cfg and serves it on ln (or on
+// cfg.Port when ln is nil) until ctx is cancelled, then shuts down:
+// the HTTP server drains, scheduled jobs stop, background work started
+// from the server context stops, and the KV store and access log are
+// closed, all within cfg.ShutdownTimeout.
+func run(ctx context.Context, cfg *config.Config, ln net.Listener) error {
+	slog.Info("Trifle build", buildinfo.Get().LogAttrs()...)
+
+	// Initialize KV store
+	kvStore, err := kv.NewStore(cfg.DataDir)
+	if err != nil {
+		return fmt.Errorf("failed to initialize KV store: %w", err)
+	}
 
-	slog.Info("Storage initialized successfully", "dataDir", dataDir)
+	slog.Info("Storage initialized successfully", "dataDir", cfg.DataDir)
+
+	// Operator-supplied display name words replace the built-in lists
+	if cfg.WordListDir != "" {
+		if err := namegen.LoadDir(cfg.WordListDir); err != nil {
+			return err
+		}
+		slog.Info("Loaded display name words", "dir", cfg.WordListDir,
+			"adjectives", len(namegen.Adjectives), "nouns", len(namegen.Nouns))
+	}
 
 	// Initialize session manager (for OAuth)
-	sessionMgr := auth.NewSessionManager(isProduction)
+	sessionMgr := auth.NewSessionManager(cfg.Production, cfg.SessionLifetime)
 
-	// Get OAuth credentials
-	clientID, clientSecret, err3 := auth.GetOAuthCredentials()
-	if err3 != nil {
-		slog.Error("Failed to get OAuth credentials", "error", err3)
-		os.Exit(1)
+	// Admin-only endpoints accept an admin session or the admin bearer token
+	adminGate := auth.NewAdminGate(sessionMgr, cfg.AdminEmails, cfg.AdminToken)
+
+	// Maintenance mode refuses writes during migrations; admins toggle it at runtime
+	maintenanceMode := maintenance.New(cfg.Maintenance)
+
+	// Prometheus metrics for HTTP traffic, KV operations, and sessions
+	appMetrics := metrics.New()
+	kvStore.SetObserver(appMetrics)
+	appMetrics.GaugeFunc("sessions", "Sessions held in memory.", func() float64 {
+		return float64(sessionMgr.Count())
+	})
+
+	// Periodic background work, started once the server is up
+	scheduler := jobs.New()
+	if err := scheduler.Register("session-cleanup", 10*time.Minute, time.Minute, func(ctx context.Context) error {
+		if removed := sessionMgr.RemoveExpired(); removed > 0 {
+			slog.Info("Removed expired sessions", "count", removed)
+		}
+		return nil
+	}); err != nil {
+		return err
 	}
 
 	// Load email allowlist
-	allowlistPath := fmt.Sprintf("%s/allowlist.txt", dataDir)
-	allowlist, err4 := auth.NewAllowlist(allowlistPath)
-	if err4 != nil {
-		slog.Error("Failed to load allowlist", "error", err4, "path", allowlistPath)
-		os.Exit(1)
+	allowlistPath := filepath.Join(cfg.DataDir, "allowlist.txt")
+	allowlist, err := auth.NewAllowlist(allowlistPath)
+	if err != nil {
+		return fmt.Errorf("failed to load allowlist %s: %w", allowlistPath, err)
 	}
 
 	// Initialize OAuth config
-	oauthConfig := auth.NewOAuthConfig(clientID, clientSecret, redirectURL, sessionMgr, allowlist)
+	oauthConfig := auth.NewOAuthConfig(cfg.GoogleClientID, cfg.GoogleClientSecret, cfg.RedirectURL, sessionMgr, allowlist)
 
 	// Set up web filesystem
-	webContent, err5 := fs.Sub(webFS, "web")
-	if err5 != nil {
-		slog.Error("Failed to get web subdirectory", "error", err5)
-		os.Exit(1)
+	webContent, err := fs.Sub(webFS, "web")
+	if err != nil {
+		return fmt.Errorf("failed to get web subdirectory: %w", err)
+	}
+	// The browser generates display names from the server's word lists
+	staticAssets, err := assets.New(webContent, assets.File{
+		Path:    namegen.BrowserModulePath,
+		Content: namegen.BrowserModule(),
+	})
+	if err != nil {
+		return err
 	}
 
 	// Set up HTTP router
 	mux := http.NewServeMux()
 
+	// Routes declare their methods; others get 405, and OPTIONS gets 204,
+	// both with an Allow header and before any auth check
+	readOnly := middleware.Methods(http.MethodGet, http.MethodHead)
+	readWrite := middleware.Methods(http.MethodGet, http.MethodHead, http.MethodPost)
+
+	// Liveness and readiness probes - NO AUTH REQUIRED
+	mux.Handle("/healthz", readOnly(http.HandlerFunc(health.HandleHealthz)))
+	mux.Handle("/readyz", readOnly(health.HandleReadyz(map[string]health.Check{
+		"kv": func(ctx context.Context) error { return kvStore.CheckWritable() },
+	}, maintenanceMode.Active)))
+
+	// Metrics (admin only, since they reveal usage)
+	mux.Handle("/metrics", readOnly(adminGate.Require(appMetrics.Handler())))
+	mux.Handle("/admin/jobs", readOnly(adminGate.Require(http.HandlerFunc(scheduler.HandleStatus))))
+	mux.Handle("/admin/maintenance", readWrite(adminGate.Require(http.HandlerFunc(maintenanceMode.HandleToggle))))
+	mux.Handle("/admin/users", readOnly(adminGate.Require(kv.HandleUsers(kvStore, sessionMgr.LastSeen))))
+
+	// Profiling and internal counters (admin only)
+	debugHandler := readWrite(adminGate.Require(diag.Handler(map[string]diag.Var{
+		"sessions":    func() any { return sessionMgr.Count() },
+		"maintenance": func() any { return maintenanceMode.Active() },
+		"jobs":        func() any { return scheduler.Status() },
+	})))
+	mux.Handle("/debug/pprof/", debugHandler)
+	mux.Handle("/debug/vars", debugHandler)
+
 	// Home page - NO AUTH REQUIRED (local-first!)
-	// Serves the static index.html which uses IndexedDB
-	mux.Handle("/", http.FileServer(http.FS(webContent)))
+	// Serves the static index.html which uses IndexedDB. Unknown paths fall
+	// back to the app shell for browser navigations, JSON for API paths, and
+	// a branded 404 page otherwise.
+	mux.Handle("/", readOnly(staticAssets.Fallback(assets.FallbackOptions{
+		APIPrefixes:      []string{"/api/", "/kv/", "/kvlist/", "/kvsync/", "/kvexists"},
+		ReservedPrefixes: []string{"/auth/", "/css/", "/js/"},
+	})))
+	mux.Handle("/asset-manifest.json", readOnly(http.HandlerFunc(staticAssets.HandleManifest)))
 
 	// Auth routes (optional, only for sync)
-	mux.HandleFunc("/auth/login", oauthConfig.HandleLogin)
-	mux.HandleFunc("/auth/callback", oauthConfig.HandleCallback)
-	mux.HandleFunc("/auth/logout", oauthConfig.HandleLogout)
-	mux.HandleFunc("/api/whoami", auth.HandleWhoAmI(sessionMgr))
+	mux.Handle("/auth/login", readOnly(http.HandlerFunc(oauthConfig.HandleLogin)))
+	mux.Handle("/auth/callback", readOnly(http.HandlerFunc(oauthConfig.HandleCallback)))
+	mux.Handle("/auth/logout", readWrite(http.HandlerFunc(oauthConfig.HandleLogout)))
+
+	// API description for third-party clients, browsable by admins
+	openAPI, err := apiSpec().Handler()
+	if err != nil {
+		return err
+	}
+	mux.Handle("/api/openapi.json", readOnly(openAPI))
+	mux.Handle("/admin/api-docs", readOnly(adminGate.Require(apidoc.UIHandler("/api/openapi.json"))))
 
-	// KV API handlers (require authentication)
+	// API and KV endpoints (KV requires authentication), shared with the
+	// client tests
 	kvHandlers := kv.NewHandlers(kvStore)
+	kvHandlers.SetLegacyKeys(cfg.LegacyKeys)
+	kvHandlers.SetMaxValueBytes(int64(cfg.MaxValueMB) << 20)
+	routes.API{
+		Sessions:      sessionMgr,
+		Maintenance:   maintenanceMode,
+		KV:            kvHandlers,
+		UploadTimeout: cfg.UploadTimeout,
+	}.Register(mux)
 
-	// Create session adapter for KV middleware
-	kvSessionAdapter := kv.NewSessionManagerAdapter(func(r *http.Request) (string, bool, error) {
-		session, err := sessionMgr.GetSession(r)
-		if err != nil {
-			return "", false, err
+	// Serve static files from embedded web directory
+	mux.Handle("/css/", readOnly(staticAssets))
+	mux.Handle("/js/", readOnly(staticAssets))
+
+	// Refuse writes while in maintenance mode (counted in metrics as 503s)
+	handler := appMetrics.Middleware(maintenanceMode.Middleware("/api/", "/kv/")(mux))
+
+	// Cross-origin access for alternative clients, if configured
+	if len(cfg.CORSOrigins) > 0 {
+		handler = middleware.CORS(middleware.CORSOptions{
+			AllowedOrigins:   cfg.CORSOrigins,
+			AllowedMethods:   cfg.CORSMethods,
+			AllowedHeaders:   cfg.CORSHeaders,
+			AllowCredentials: cfg.CORSCredentials,
+			MaxAge:           cfg.CORSMaxAge,
+		})(handler)
+	}
+
+	// Compress compressible responses (JSON, HTML, JS, CSS) for clients that
+	// accept it. Profiles under /debug/ stream as they're generated, so they
+	// skip compression (which buffers) entirely.
+	uncompressed := handler
+	compressed := middleware.Compress(middleware.DefaultCompressMinSize)(handler)
+	handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/debug/") {
+			uncompressed.ServeHTTP(w, r)
+			return
 		}
-		return session.Email, session.Authenticated, nil
+		compressed.ServeHTTP(w, r)
 	})
 
-	requireAuth := kv.RequireAuth(kvSessionAdapter)
+	// Log requests with status, size, and the user when logged in.
+	// Static assets and probes are sampled so they don't drown out API traffic.
+	accessLogOpts := middleware.LoggingOptions{
+		Identify: func(r *http.Request) string {
+			session, err := sessionMgr.PeekSession(r)
+			if err != nil || !session.Authenticated {
+				return ""
+			}
+			return session.Email
+		},
+		Quiet:       isRoutineRequest,
+		SampleEvery: 100,
+	}
 
-	// KV endpoints
-	mux.HandleFunc("/kv/", requireAuth(kvHandlers.HandleKV))
-	mux.HandleFunc("/kvlist/", requireAuth(kvHandlers.HandleList))
+	// Optionally write the access log to its own rotating file, e.g. for
+	// goaccess. A dedicated log keeps every request, unsampled.
+	var accessLogOut io.Writer = os.Stdout
+	var accessLog *logfile.File
+	if cfg.AccessLog != "" && cfg.AccessLog != "stdout" {
+		accessLog, err = logfile.Open(cfg.AccessLog, logfile.Options{
+			MaxSize: int64(cfg.AccessLogMaxSizeMB) << 20,
+			MaxAge:  cfg.AccessLogMaxAge,
+			Keep:    cfg.AccessLogKeep,
+		})
+		if err != nil {
+			return err
+		}
+		accessLogOut = accessLog
+		accessLogOpts.Logger = slog.New(slog.NewTextHandler(accessLog, nil))
+		accessLogOpts.SampleEvery = 0
+	}
+	if cfg.AccessLogFormat == "combined" {
+		accessLogOpts.Combined = accessLogOut
+	}
+	logging := middleware.Logging(accessLogOpts)
 
-	// Serve static files from embedded web directory
-	mux.Handle("/css/", http.FileServer(http.FS(webContent)))
-	mux.Handle("/js/", http.FileServer(http.FS(webContent)))
+	// Behind Caddy/nginx, take the client IP and scheme from X-Forwarded-*
+	// headers set by trusted proxies (and only them)
+	trustedProxies, err := middleware.ParseTrustedProxies(cfg.TrustedProxies)
+	if err != nil {
+		return err
+	}
 
 	// Create HTTP server with logging middleware
 	server := &http.Server{
-		Addr:         fmt.Sprintf(":%s", port),
-		Handler:      loggingMiddleware(mux),
-		ReadTimeout:  15 * time.Second,
-		WriteTimeout: 15 * time.Second,
-		IdleTimeout:  60 * time.Second,
+		Addr:              fmt.Sprintf(":%s", cfg.Port),
+		Handler:           middleware.TrustedProxies(trustedProxies)(logging(handler)),
+		ReadTimeout:       cfg.ReadTimeout,
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		WriteTimeout:      cfg.WriteTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
+		MaxHeaderBytes:    cfg.MaxHeaderBytes,
 	}
 
-	// Start server in goroutine
-	go func() {
-		serverURL := fmt.Sprintf("http://localhost:%s/", port)
-		slog.Info("Trifle server starting", "url", serverURL)
-		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			slog.Error("Server failed", "error", err)
-			os.Exit(1)
+	// Background work derives from serverCtx, which is cancelled once the
+	// HTTP server has drained
+	serverCtx, cancelServer := context.WithCancel(context.Background())
+	defer cancelServer()
+	server.BaseContext = func(net.Listener) context.Context { return serverCtx }
+	scheduler.Start(serverCtx)
+
+	if ln == nil {
+		ln, err = net.Listen("tcp", server.Addr)
+		if err != nil {
+			return fmt.Errorf("failed to listen: %w", err)
 		}
+	}
+
+	serveErr := make(chan error, 1)
+	go func() {
+		slog.Info("Trifle server starting", "url", fmt.Sprintf("http://localhost:%s/", cfg.Port))
+		serveErr <- server.Serve(ln)
 	}()
 
-	// Wait for interrupt signal
-	sigCh := make(chan os.Signal, 1)
-	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
-	<-sigCh
+	select {
+	case err := <-serveErr:
+		return fmt.Errorf("server failed: %w", err)
+	case <-ctx.Done():
+	}
 
 	slog.Info("Shutting down server...")
+	start := time.Now()
 
-	// Graceful shutdown
-	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	// Graceful shutdown, in dependency order, within one overall deadline
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
 	defer cancel()
 
-	if err := server.Shutdown(ctx); err != nil {
-		slog.Error("Server shutdown error", "error", err)
+	var errs []error
+	errs = append(errs, shutdownStage(shutdownCtx, "http", server.Shutdown))
+	errs = append(errs, shutdownStage(shutdownCtx, "jobs", scheduler.Stop))
+	errs = append(errs, shutdownStage(shutdownCtx, "background", func(context.Context) error {
+		cancelServer()
+		return nil
+	}))
+	errs = append(errs, shutdownStage(shutdownCtx, "kv", func(context.Context) error {
+		return kvStore.Close()
+	}))
+	if accessLog != nil {
+		errs = append(errs, shutdownStage(shutdownCtx, "access-log", func(context.Context) error {
+			return accessLog.Close()
+		}))
 	}
 
-	slog.Info("Server stopped")
+	slog.Info("Server stopped", "duration", time.Since(start))
+	return errors.Join(errs...)
 }
 
-// loggingMiddleware logs HTTP requests
-func loggingMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		start := time.Now()
-		next.ServeHTTP(w, r)
-		duration := time.Since(start)
-		slog.Info("HTTP request",
-			"method", r.Method,
-			"path", r.URL.Path,
-			"duration", duration,
-		)
-	})
+// shutdownStage runs one step of graceful shutdown, logging its duration.
+// A stage still running when ctx expires is abandoned.
+func shutdownStage(ctx context.Context, name string, fn func(context.Context) error) error {
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() { done <- fn(ctx) }()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	if err != nil {
+		slog.Error("Shutdown stage failed", "stage", name, "duration", time.Since(start), "error", err)
+		return fmt.Errorf("shutdown %s: %w", name, err)
+	}
+	slog.Info("Shutdown stage complete", "stage", name, "duration", time.Since(start))
+	return nil
+}
+
+// isRoutineRequest reports whether a request is for an embedded static file
+// or a health probe or metrics scrape
+func isRoutineRequest(r *http.Request) bool {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+	path := r.URL.Path
+	return path == "/healthz" ||
+		path == "/readyz" ||
+		path == "/metrics" ||
+		strings.HasPrefix(path, "/css/") ||
+		strings.HasPrefix(path, "/js/") ||
+		path == "/sw.js" ||
+		path == "/favicon.ico"
 }
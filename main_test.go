@@ -0,0 +1,212 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/fs"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/goleak"
+
+	"github.com/zellyn/trifle/internal/assets"
+	"github.com/zellyn/trifle/internal/config"
+)
+
+func TestRun_GracefulShutdown(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	cfg := config.Default()
+	cfg.DataDir = t.TempDir()
+	cfg.GoogleClientID = "test-client-id"
+	cfg.GoogleClientSecret = "test-client-secret"
+	cfg.RedirectURL = "http://localhost:3000/auth/callback"
+	cfg.ShutdownTimeout = 5 * time.Second
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- run(ctx, cfg, ln) }()
+
+	client := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+	for _, path := range []string{"/healthz", "/readyz", "/"} {
+		resp, err := client.Get("http://" + ln.Addr().String() + path)
+		if err != nil {
+			t.Fatalf("GET %s failed: %v", path, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("GET %s: expected 200, got %d", path, resp.StatusCode)
+		}
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("run returned error: %v", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("run did not return after shutdown")
+	}
+}
+
+// startTestServer runs the server with a test configuration until the test
+// ends and returns its address
+func startTestServer(t *testing.T) string {
+	t.Helper()
+	cfg := config.Default()
+	cfg.DataDir = t.TempDir()
+	cfg.GoogleClientID = "test-client-id"
+	cfg.GoogleClientSecret = "test-client-secret"
+	cfg.RedirectURL = "http://localhost:3000/auth/callback"
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- run(ctx, cfg, ln) }()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+	return ln.Addr().String()
+}
+
+func TestRun_AllowedMethods(t *testing.T) {
+	addr := startTestServer(t)
+
+	routes := []struct {
+		path  string
+		allow string
+	}{
+		{"/api/whoami", "GET, HEAD, OPTIONS"},
+		{"/api/status", "GET, HEAD, OPTIONS"},
+		{"/api/version", "GET, HEAD, OPTIONS"},
+		{"/api/openapi.json", "GET, HEAD, OPTIONS"},
+		{"/kv/file/ab/cd/abcd", "GET, HEAD, PUT, DELETE, OPTIONS"},
+		{"/kvlist/file", "GET, HEAD, OPTIONS"},
+		{"/kvsync/manifest", "POST, OPTIONS"},
+		{"/kvexists", "POST, OPTIONS"},
+		{"/admin/users", "GET, HEAD, OPTIONS"},
+		{"/admin/jobs", "GET, HEAD, OPTIONS"},
+		{"/admin/maintenance", "GET, HEAD, POST, OPTIONS"},
+		{"/admin/api-docs", "GET, HEAD, OPTIONS"},
+		{"/metrics", "GET, HEAD, OPTIONS"},
+		{"/debug/vars", "GET, HEAD, POST, OPTIONS"},
+		{"/healthz", "GET, HEAD, OPTIONS"},
+		{"/readyz", "GET, HEAD, OPTIONS"},
+		{"/auth/login", "GET, HEAD, OPTIONS"},
+		{"/auth/callback", "GET, HEAD, OPTIONS"},
+		{"/auth/logout", "GET, HEAD, POST, OPTIONS"},
+		{"/", "GET, HEAD, OPTIONS"},
+		{"/asset-manifest.json", "GET, HEAD, OPTIONS"},
+		{"/css/app.css", "GET, HEAD, OPTIONS"},
+		{"/js/app.js", "GET, HEAD, OPTIONS"},
+	}
+	client := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+	for _, route := range routes {
+		// Neither is authenticated: OPTIONS and 405 come before auth
+		for method, wantStatus := range map[string]int{
+			http.MethodOptions: http.StatusNoContent,
+			http.MethodPatch:   http.StatusMethodNotAllowed,
+		} {
+			req, _ := http.NewRequest(method, "http://"+addr+route.path, nil)
+			resp, err := client.Do(req)
+			if err != nil {
+				t.Fatalf("%s %s failed: %v", method, route.path, err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != wantStatus {
+				t.Errorf("%s %s: expected %d, got %d", method, route.path, wantStatus, resp.StatusCode)
+			}
+			if got := resp.Header.Get("Allow"); got != route.allow {
+				t.Errorf("%s %s: expected Allow %q, got %q", method, route.path, route.allow, got)
+			}
+		}
+	}
+
+	// Admin listings still need an admin
+	resp, err := client.Get("http://" + addr + "/admin/users")
+	if err != nil {
+		t.Fatalf("GET /admin/users failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("GET /admin/users: expected 401 without a session, got %d", resp.StatusCode)
+	}
+}
+
+func TestShutdownStage_Deadline(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	release := make(chan struct{})
+	defer close(release)
+
+	err := shutdownStage(ctx, "stuck", func(context.Context) error {
+		<-release
+		return nil
+	})
+	if err == nil {
+		t.Errorf("Expected an error for a stage that outlives the deadline")
+	}
+}
+
+func TestAssetManifest_MatchesEmbeddedFS(t *testing.T) {
+	webContent, err := fs.Sub(webFS, "web")
+	if err != nil {
+		t.Fatalf("Failed to get web subdirectory: %v", err)
+	}
+	staticAssets, err := assets.New(webContent)
+	if err != nil {
+		t.Fatalf("Failed to load assets: %v", err)
+	}
+	manifest := staticAssets.Manifest()
+
+	files := 0
+	err = fs.WalkDir(webContent, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		files++
+
+		entry, ok := manifest.Assets["/"+name]
+		if !ok {
+			t.Errorf("Embedded file %s is missing from the manifest", name)
+			return nil
+		}
+		content, err := fs.ReadFile(webContent, name)
+		if err != nil {
+			return err
+		}
+		// Pages with {{asset}} references are listed as rendered
+		if asset, _ := staticAssets.Lookup("/" + name); strings.Contains(string(content), "{{asset ") {
+			if entry.Hash != asset.Hash || entry.Size != asset.Size {
+				t.Errorf("Manifest entry for rendered page %s doesn't match what's served", name)
+			}
+			return nil
+		}
+		sum := sha256.Sum256(content)
+		if entry.Hash != hex.EncodeToString(sum[:]) || entry.Size != int64(len(content)) {
+			t.Errorf("Manifest entry for %s = %+v, want hash %x and size %d", name, entry, sum, len(content))
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Failed to walk embedded files: %v", err)
+	}
+	if len(manifest.Assets) != files {
+		t.Errorf("Expected %d manifest entries, one per embedded file, got %d", files, len(manifest.Assets))
+	}
+}
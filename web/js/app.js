@@ -6,7 +6,7 @@
 import { generateName } from './namegen.js';
 import { TrifleDB } from './db.js';
 import { SyncManager } from './sync-kv.js';
-import { showError } from './notifications.js';
+import { showError, watchServerStatus } from './notifications.js';
 import { generateAvatar } from './avatar.js';
 
 // Current user (cached after init)
@@ -26,6 +26,9 @@ async function init() {
             window.history.replaceState({}, '', '/');
         }
 
+        // Warn while the server refuses writes
+        watchServerStatus();
+
         // Initialize user (create if doesn't exist)
         await initUser();
 
@@ -2,7 +2,7 @@
 // Handles file tree, Ace editor, Pyodide integration, and auto-save
 
 import { TrifleDB } from './db.js';
-import { showError, showInfo } from './notifications.js';
+import { showError, showInfo, watchServerStatus } from './notifications.js';
 
 // Constants
 const SYNC_CHECK_INTERVAL_MS = 10000;  // Check for offline sync every 10 seconds
@@ -239,6 +239,9 @@ async function init() {
         return;
     }
 
+    // Warn while the server refuses writes
+    watchServerStatus();
+
     state.trifleId = getTrifleId();
 
     if (!state.trifleId) {
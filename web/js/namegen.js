@@ -4,7 +4,9 @@
  * Generates adjective-noun combinations like "dapper-panda" or "jolly-tiger"
  * for use as temporary display names in the Trifle playground.
  *
- * Uses the same word lists as the Go backend for consistency.
+ * The adjective, noun, and exclusion lists come from words.js, which the
+ * server generates from internal/namegen/words/ (or the wordlist-dir
+ * override), so the browser and the Go backend always agree.
  *
  * @example
  * // Generate a random name
@@ -24,35 +26,30 @@
  * console.log(`Can generate ${adjectives.length * nouns.length} unique names`);
  */
 
+import { ADJECTIVES, NOUNS, EXCLUDED_NAMES, EXCLUDED_SUBSTRINGS } from './words.js';
+
+const EXCLUDED = new Set(EXCLUDED_NAMES);
+
 /**
- * List of adjectives with a Victorian/19th century literary flavor
- * Matches the Go backend word list in internal/namegen/namegen.go
+ * How often generateName redraws an excluded name (maxAttempts in Go)
  */
-const ADJECTIVES = [
-  "dapper", "jolly", "keen", "clever", "bold", "wise", "gallant", "stalwart",
-  "intrepid", "valiant", "earnest", "sprightly", "hale", "robust", "jaunty", "plucky",
-  "bonny", "dashing", "stout", "resolute", "steadfast", "vigilant", "mirthful", "sanguine",
-  "blithe", "jovial", "genial", "affable", "prudent", "sagacious", "wily", "canny",
-  "astute", "dauntless", "undaunted", "comely", "winsome", "droll", "whimsical", "fanciful",
-  "industrious", "diligent", "urbane", "refined", "courteous", "genteel", "spirited", "animated",
-  "vivacious", "formidable", "redoubtable", "singular", "peculiar", "quaint", "ardent", "fervent",
-  "hearty", "merry", "noble", "bright", "brisk", "capable", "worthy", "able",
-];
+const MAX_ATTEMPTS = 100;
 
 /**
- * List of animal nouns
- * Matches the Go backend word list in internal/namegen/namegen.go
+ * Report whether a name is excluded, matching namegen.IsExcluded in Go:
+ * an exact excluded name, or an excluded substring anywhere in the name
+ * with its hyphens removed (so words can't combine into one)
+ *
+ * @param {string} name - A generated name like "stout-walrus"
+ * @returns {boolean} True if the name must not be used
  */
-const NOUNS = [
-  "panda", "tiger", "eagle", "dolphin", "falcon", "turtle", "penguin", "raccoon",
-  "otter", "badger", "raven", "lynx", "beaver", "coyote", "gecko", "hamster",
-  "iguana", "jaguar", "koala", "lemur", "monkey", "narwhal", "owl", "parrot",
-  "quail", "rabbit", "salmon", "toucan", "unicorn", "viper", "walrus", "yak",
-  "zebra", "alpaca", "bison", "camel", "dragonfly", "elephant", "flamingo", "giraffe",
-  "hedgehog", "ibex", "jellyfish", "kangaroo", "llama", "meerkat", "nautilus", "octopus",
-  "platypus", "quokka", "starfish", "tapir", "urchin", "vulture", "wombat", "axolotl",
-  "butterfly", "chameleon", "firefly", "hummingbird", "mantis", "peacock", "seahorse", "sparrow",
-];
+export function isExcluded(name) {
+  if (EXCLUDED.has(name)) {
+    return true;
+  }
+  const joined = name.replaceAll('-', '');
+  return EXCLUDED_SUBSTRINGS.some(sub => joined.includes(sub));
+}
 
 /**
  * Simple seeded random number generator (Linear Congruential Generator)
@@ -75,21 +72,42 @@ function createSeededRandom(seed) {
  * Generate a random display name
  *
  * @param {number} [seed] - Optional seed for deterministic generation (useful for testing)
- * @returns {string} A random name in "adjective-noun" format
+ * @param {number} [words=2] - Number of words: 2 ("adjective-noun") or 3 ("adjective-adjective-noun")
+ * @returns {string} A random name in "adjective-noun" or "adjective-adjective-noun" format
+ * @throws {Error} If the word lists are too short, or every attempt drew an excluded name
  *
  * @example
  * generateName(); // "intrepid-dolphin"
  * generateName(12345); // Always returns same name for same seed
+ * generateName(undefined, 3); // "jolly-intrepid-dolphin"
  */
-export function generateName(seed) {
+export function generateName(seed, words = 2) {
+  // The server rejects duplicate words, so this many distinct adjectives exist
+  if (ADJECTIVES.length < words - 1 || NOUNS.length === 0) {
+    throw new Error(`Word lists too short for a ${words}-word name`);
+  }
+
   const random = seed !== undefined
     ? createSeededRandom(seed)
     : Math.random;
 
-  const adjective = ADJECTIVES[Math.floor(random() * ADJECTIVES.length)];
-  const noun = NOUNS[Math.floor(random() * NOUNS.length)];
-
-  return `${adjective}-${noun}`;
+  // Redraw excluded names, up to the same bound as the Go backend
+  for (let attempt = 0; attempt < MAX_ATTEMPTS; attempt++) {
+    // Adjectives in a three-word name are distinct, matching the Go backend
+    const adjectives = [];
+    while (adjectives.length < words - 1) {
+      const adjective = ADJECTIVES[Math.floor(random() * ADJECTIVES.length)];
+      if (!adjectives.includes(adjective)) {
+        adjectives.push(adjective);
+      }
+    }
+    const noun = NOUNS[Math.floor(random() * NOUNS.length)];
+    const name = [...adjectives, noun].join('-');
+    if (!isExcluded(name)) {
+      return name;
+    }
+  }
+  throw new Error(`No acceptable name after ${MAX_ATTEMPTS} attempts; check the exclusion list`);
 }
 
 /**
@@ -2,12 +2,14 @@
 // Shows dismissible messages at the top of the page
 
 const DISMISS_ANIMATION_DURATION = 300; // milliseconds
+const STATUS_POLL_INTERVAL = 60000; // milliseconds
 
 /**
  * Show a notification message
  * @param {string} message - The message to display
  * @param {string} type - Type of message: 'error', 'success', 'info' (default: 'info')
  * @param {number} autoDismiss - Auto-dismiss after N milliseconds (0 = no auto-dismiss)
+ * @returns {HTMLElement} The notification element
  */
 export function showMessage(message, type = 'info', autoDismiss = 0) {
     // Get or create notification container
@@ -69,6 +71,8 @@ export function showMessage(message, type = 'info', autoDismiss = 0) {
         // Store timeout ID so we can clear it on manual dismissal
         notification.dataset.timeoutId = timeoutId;
     }
+
+    return notification;
 }
 
 /**
@@ -116,3 +120,32 @@ export function showSuccess(message, autoDismiss = 3000) {
 export function showInfo(message, autoDismiss = 5000) {
     showMessage(message, 'info', autoDismiss);
 }
+
+/**
+ * Poll /api/status and show a banner while the server is in maintenance
+ * mode (changes can't be synced, but everything local keeps working)
+ */
+export function watchServerStatus() {
+    let banner = null;
+
+    async function check() {
+        try {
+            const response = await fetch('/api/status');
+            if (!response.ok) {
+                return;
+            }
+            const status = await response.json();
+            if (status.maintenance && !banner) {
+                banner = showMessage('Trifling is undergoing maintenance. Your work is saved locally and will sync when it is over.', 'info');
+            } else if (!status.maintenance && banner) {
+                dismissNotification(banner);
+                banner = null;
+            }
+        } catch {
+            // Offline; nothing to report
+        }
+    }
+
+    check();
+    setInterval(check, STATUS_POLL_INTERVAL);
+}
@@ -1,29 +1,12 @@
 // Trifling Service Worker - Enables offline functionality
-const CACHE_VERSION = 'v60';
-const CACHE_NAME = `trifling-${CACHE_VERSION}`;
-
-// Resources to cache on install
-const STATIC_CACHE = [
-    '/',
-    '/index.html',
-    '/editor.html',
-    '/profile.html',
-    '/data.html',
-    '/about.html',
-    '/css/app.css',
-    '/js/app.js',
-    '/js/editor.js',
-    '/js/profile.js',
-    '/js/avatar.js',
-    '/js/avatar-editor.js',
-    '/js/data.js',
-    '/js/db.js',
-    '/js/namegen.js',
-    '/js/notifications.js',
-    '/js/worker.js',
-    '/js/terminal.js',
-    '/js/sync-kv.js'
-];
+//
+// The app shell is precached from /asset-manifest.json, which the server
+// generates from the embedded files. Each deploy gets its own cache, named
+// after the manifest version; CDN resources are versioned by URL and live
+// in a separate cache that survives deploys.
+const CACHE_PREFIX = 'trifling-';
+const CDN_CACHE_NAME = 'trifling-cdn-v1';
+const MANIFEST_URL = '/asset-manifest.json';
 
 // CDN resources to cache (Ace Editor and Pyodide)
 const CDN_CACHE = [
@@ -37,26 +20,86 @@ const CDN_CACHE = [
     'https://cdn.jsdelivr.net/pyodide/v0.28.3/full/pyodide-lock.json'
 ];
 
+// Name of the complete app shell cache currently in use, once known
+let currentCacheName = null;
+
+// Refresh in progress, so concurrent navigations share one
+let refreshing = null;
+
+// Precache the app shell listed in the asset manifest, if this version
+// isn't cached yet, then switch to it and delete older versions. The
+// manifest is stored last and marks the cache as complete.
+function refreshAppShell() {
+    if (refreshing) {
+        return refreshing;
+    }
+    refreshing = (async () => {
+        const response = await fetch(MANIFEST_URL, { cache: 'no-cache' });
+        if (!response.ok) {
+            throw new Error(`Failed to fetch asset manifest: ${response.status}`);
+        }
+        const manifest = await response.clone().json();
+        const cacheName = CACHE_PREFIX + manifest.version;
+        const cache = await caches.open(cacheName);
+
+        if (!(await cache.match(MANIFEST_URL))) {
+            console.log('[Service Worker] Caching app shell version', manifest.version);
+
+            // Plain paths (used by ES module imports) and fingerprinted URLs
+            // (used by pages); the worker script itself is never cached
+            const urls = new Set(['/']);
+            for (const [path, entry] of Object.entries(manifest.assets)) {
+                if (path === '/sw.js') {
+                    continue;
+                }
+                urls.add(path);
+                urls.add(entry.url);
+            }
+            await cache.addAll([...urls]);
+            await cache.put(MANIFEST_URL, response);
+        }
+
+        currentCacheName = cacheName;
+        await deleteOldCaches();
+    })().finally(() => {
+        refreshing = null;
+    });
+    return refreshing;
+}
+
+// Cache CDN resources individually (they might fail, don't block on them)
+async function cacheCDNResources() {
+    const cache = await caches.open(CDN_CACHE_NAME);
+    await Promise.all(CDN_CACHE.map(async (url) => {
+        if (await cache.match(url)) {
+            return;
+        }
+        try {
+            await cache.add(url);
+        } catch (err) {
+            console.warn(`[Service Worker] Failed to cache ${url}:`, err);
+        }
+    }));
+}
+
+// Delete app shell caches other than the current one, and caches from
+// before the manifest existed
+async function deleteOldCaches() {
+    const cacheNames = await caches.keys();
+    await Promise.all(cacheNames.map((cacheName) => {
+        if (cacheName !== currentCacheName && cacheName !== CDN_CACHE_NAME && cacheName.startsWith(CACHE_PREFIX)) {
+            console.log('[Service Worker] Deleting old cache:', cacheName);
+            return caches.delete(cacheName);
+        }
+    }));
+}
+
 // Install event - cache all resources
 self.addEventListener('install', (event) => {
     console.log('[Service Worker] Installing...');
 
     event.waitUntil(
-        caches.open(CACHE_NAME).then((cache) => {
-            console.log('[Service Worker] Caching static assets and CDN resources');
-
-            // Cache static assets
-            const staticPromise = cache.addAll(STATIC_CACHE);
-
-            // Cache CDN resources individually (they might fail, don't block on them)
-            const cdnPromises = CDN_CACHE.map((url) =>
-                cache.add(url).catch((err) => {
-                    console.warn(`[Service Worker] Failed to cache ${url}:`, err);
-                })
-            );
-
-            return Promise.all([staticPromise, ...cdnPromises]);
-        }).then(() => {
+        Promise.all([refreshAppShell(), cacheCDNResources()]).then(() => {
             console.log('[Service Worker] Installation complete');
             // Skip waiting to activate immediately
             return self.skipWaiting();
@@ -69,15 +112,8 @@ self.addEventListener('activate', (event) => {
     console.log('[Service Worker] Activating...');
 
     event.waitUntil(
-        caches.keys().then((cacheNames) => {
-            return Promise.all(
-                cacheNames.map((cacheName) => {
-                    if (cacheName !== CACHE_NAME) {
-                        console.log('[Service Worker] Deleting old cache:', cacheName);
-                        return caches.delete(cacheName);
-                    }
-                })
-            );
+        refreshAppShell().catch((err) => {
+            console.warn('[Service Worker] Failed to refresh app shell:', err);
         }).then(() => {
             console.log('[Service Worker] Activation complete');
             // Claim all clients immediately
@@ -95,11 +131,20 @@ self.addEventListener('fetch', (event) => {
 
     const url = new URL(event.request.url);
 
-    // NEVER cache API endpoints - they need fresh data
-    if (url.pathname.startsWith('/api/')) {
+    // NEVER cache API endpoints - they need fresh data. The manifest is
+    // revalidated by refreshAppShell, not served from the cache.
+    if (url.pathname.startsWith('/api/') || url.pathname === MANIFEST_URL) {
         return; // Let it go to network
     }
 
+    // sw.js doesn't change between deploys, so check for a new app shell
+    // on each page load. An unchanged manifest revalidates with a 304.
+    if (event.request.mode === 'navigate') {
+        event.waitUntil(refreshAppShell().catch((err) => {
+            console.warn('[Service Worker] Failed to refresh app shell:', err);
+        }));
+    }
+
     event.respondWith(
         caches.match(event.request).then((cachedResponse) => {
             if (cachedResponse) {
@@ -138,8 +183,8 @@ self.addEventListener('fetch', (event) => {
                 const responseToCache = response.clone();
 
                 // Cache the response for future use (but not API endpoints)
-                if (!url.pathname.startsWith('/api/')) {
-                    caches.open(CACHE_NAME).then((cache) => {
+                if (!url.pathname.startsWith('/api/') && currentCacheName) {
+                    caches.open(currentCacheName).then((cache) => {
                         cache.put(event.request, responseToCache);
                     });
                 }